@@ -0,0 +1,447 @@
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AdminHandler lists the sessions in a store and lets them be revoked or extended.
+//
+// Routes are relative to where the handler is mounted (use http.StripPrefix):
+//
+//	GET  /                     paginated list of sessions (?page=N)
+//	GET  /{handle}             a single session
+//	POST /{handle}/revoke      delete the session
+//	POST /{handle}/extend      push the expiry back by the "duration" form value (default 1h)
+//
+// Session IDs are credentials so sessions are shown and addressed by a handle, a keyed hash of the ID.
+// Mount it on a path ending in "/" so the relative links in the HTML pages resolve.
+// Responses are HTML unless the request has ?format=json or accepts application/json.
+type AdminHandler struct {
+	Store SessionStore
+
+	// Called before every request, the request is refused unless it returns true.
+	// A nil Authorize refuses everything so the handler can't be mounted unprotected by mistake.
+	Authorize func(*http.Request) bool
+
+	// Checks the token on revoke and extend, the handler must be inside CSRF.Sessions.GetHandler.
+	// POSTs are refused when it's nil, for the same reason as Authorize.
+	// Pages and JSON responses carry a token to send back in the form field or header.
+	CSRF *CSRF
+
+	// Key for the session handles, a random key is used when it's not set (handles then change on restart).
+	HandleKey []byte
+	keyOnce   sync.Once
+
+	// The hooks of the SessionInfo using Store (i.e. &info.Hooks), OnDestroy is fired for revoked sessions.
+	Hooks *Hooks
+
+	// Sessions per page, defaults to 50.
+	PageSize int
+
+	// The time sessions are extended from and shown as expired against, defaults to SystemClock.
+	Clock Clock
+}
+
+type AdminSession struct {
+	Handle  string    `json:"handle"`
+	Expires time.Time `json:"expires"`
+	Expired bool      `json:"expired"`
+	Keys    []string  `json:"keys"`
+
+	CSRFToken string `json:"csrf_token,omitempty"`
+}
+
+type AdminPage struct {
+	Sessions []AdminSession `json:"sessions"`
+	Page     int            `json:"page"`
+	Pages    int            `json:"pages"`
+	Total    int            `json:"total"`
+
+	CSRFToken string `json:"csrf_token,omitempty"`
+}
+
+// What the HTML templates are executed with.
+type adminView struct {
+	Data      interface{}
+	CSRFField string
+	CSRFToken string
+}
+
+func NewAdminHandler(store SessionStore, authorize func(*http.Request) bool) *AdminHandler {
+	return &AdminHandler{
+		Store:     store,
+		Authorize: authorize,
+	}
+}
+
+func (t *AdminHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if t.Authorize == nil || !t.Authorize(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	path := strings.Trim(r.URL.Path, "/")
+	if path == "" {
+		if r.Method != "GET" {
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		t.list(w, r)
+		return
+	}
+
+	parts := strings.Split(path, "/")
+	if len(parts) > 2 {
+		http.NotFound(w, r)
+		return
+	}
+
+	handle, err := url.PathUnescape(parts[0])
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	action := ""
+	if len(parts) == 2 {
+		action = parts[1]
+	}
+
+	switch {
+	case action == "" && r.Method == "GET":
+		t.show(w, r, handle)
+	case (action == "revoke" || action == "extend") && r.Method == "POST":
+		if t.CSRF == nil || t.CSRF.Verify(r) != nil {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		if action == "revoke" {
+			t.revoke(w, r, handle)
+		} else {
+			t.extend(w, r, handle)
+		}
+	case action == "" || action == "revoke" || action == "extend":
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (t *AdminHandler) list(w http.ResponseWriter, r *http.Request) {
+	all, err := t.Store.All()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	sessions := make([]AdminSession, 0, len(all))
+	for _, s := range all {
+		as, err := t.newAdminSession(s, now(t.Clock))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		sessions = append(sessions, as)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].Handle < sessions[j].Handle
+	})
+
+	size := t.PageSize
+	if size <= 0 {
+		size = 50
+	}
+
+	page := AdminPage{
+		Page:  1,
+		Pages: (len(sessions) + size - 1) / size,
+		Total: len(sessions),
+	}
+
+	if p, err := strconv.Atoi(r.FormValue("page")); err == nil && p > 0 {
+		page.Page = p
+	}
+
+	start := (page.Page - 1) * size
+	if start > len(sessions) {
+		start = len(sessions)
+	}
+
+	end := start + size
+	if end > len(sessions) {
+		end = len(sessions)
+	}
+	page.Sessions = sessions[start:end]
+
+	page.CSRFToken, err = t.token(r)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, page)
+		return
+	}
+
+	t.render(w, adminListTemplate, page, page.CSRFToken)
+}
+
+func (t *AdminHandler) show(w http.ResponseWriter, r *http.Request, handle string) {
+	s, _, ok := t.find(w, handle)
+	if !ok {
+		return
+	}
+
+	as, err := t.newAdminSession(s, now(t.Clock))
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	as.CSRFToken, err = t.token(r)
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, as)
+		return
+	}
+
+	t.render(w, adminSessionTemplate, as, as.CSRFToken)
+}
+
+func (t *AdminHandler) revoke(w http.ResponseWriter, r *http.Request, handle string) {
+	s, id, ok := t.find(w, handle)
+	if !ok {
+		return
+	}
+
+	if err := t.Hooks.destroy(t.Store, id, s, nil); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if wantsJSON(r) {
+		writeJSON(w, map[string]string{"handle": handle, "status": "revoked"})
+		return
+	}
+
+	http.Redirect(w, r, "../", http.StatusSeeOther)
+}
+
+func (t *AdminHandler) extend(w http.ResponseWriter, r *http.Request, handle string) {
+	d := time.Hour
+	if v := r.FormValue("duration"); v != "" {
+		var err error
+		d, err = time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+	}
+
+	s, _, ok := t.find(w, handle)
+	if !ok {
+		return
+	}
+
+	expiry := s.Expiry()
+	if now := now(t.Clock); expiry.Before(now) {
+		expiry = now
+	}
+	s.SetExpiry(expiry.Add(d))
+
+	if err := t.Store.Set(s); err != nil {
+		http.Error(w, err.Error(), 500)
+		return
+	}
+
+	if wantsJSON(r) {
+		as, err := t.newAdminSession(s, now(t.Clock))
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+		writeJSON(w, as)
+		return
+	}
+
+	http.Redirect(w, r, "../"+url.PathEscape(handle), http.StatusSeeOther)
+}
+
+// Find the session with the handle, writes a 404 when there isn't one.
+func (t *AdminHandler) find(w http.ResponseWriter, handle string) (Session, string, bool) {
+	all, err := t.Store.All()
+	if err != nil {
+		http.Error(w, err.Error(), 500)
+		return nil, "", false
+	}
+
+	for _, s := range all {
+		id, err := s.ID()
+		if err != nil {
+			http.Error(w, err.Error(), 500)
+			return nil, "", false
+		}
+
+		if hmac.Equal([]byte(t.handle(id)), []byte(handle)) {
+			return s, id, true
+		}
+	}
+
+	http.Error(w, "session not found", http.StatusNotFound)
+	return nil, "", false
+}
+
+// The name the session is shown under, the first 128 bits of an HMAC of the ID.
+func (t *AdminHandler) handle(id string) string {
+	t.keyOnce.Do(func() {
+		if len(t.HandleKey) == 0 {
+			t.HandleKey = make([]byte, 32)
+			if _, err := io.ReadFull(rand.Reader, t.HandleKey); err != nil {
+				panic(err)
+			}
+		}
+	})
+
+	mac := hmac.New(sha256.New, t.HandleKey)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// A CSRF token for the admin's session, empty when there's no CSRF.
+func (t *AdminHandler) token(r *http.Request) (string, error) {
+	if t.CSRF == nil {
+		return "", nil
+	}
+	return t.CSRF.Token(r)
+}
+
+func (t *AdminHandler) render(w http.ResponseWriter, tmpl *template.Template, data interface{}, token string) {
+	view := adminView{
+		Data:      data,
+		CSRFToken: token,
+	}
+
+	if t.CSRF != nil {
+		view.CSRFField = t.CSRF.fieldName()
+	}
+
+	render(w, tmpl, view)
+}
+
+func (t *AdminHandler) newAdminSession(s Session, now time.Time) (AdminSession, error) {
+	id, err := s.ID()
+	if err != nil {
+		return AdminSession{}, err
+	}
+
+	keys, err := s.Keys()
+	if err != nil {
+		return AdminSession{}, err
+	}
+
+	as := AdminSession{
+		Handle:  t.handle(id),
+		Expires: s.Expiry(),
+		Expired: s.Expiry().Before(now),
+		Keys:    make([]string, 0, len(keys)),
+	}
+
+	for _, k := range keys {
+		as.Keys = append(as.Keys, fmt.Sprint(k))
+	}
+	sort.Strings(as.Keys)
+
+	return as, nil
+}
+
+func wantsJSON(r *http.Request) bool {
+	return r.FormValue("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+func render(w http.ResponseWriter, tmpl *template.Template, v interface{}) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := tmpl.Execute(w, v); err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+var adminFuncs = template.FuncMap{
+	"pathescape": url.PathEscape,
+	"prev": func(i int) int {
+		return i - 1
+	},
+	"next": func(i int) int {
+		return i + 1
+	},
+}
+
+var adminListTemplate = template.Must(template.New("list").Funcs(adminFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><title>Sessions</title></head>
+<body>
+<h1>Sessions ({{.Data.Total}})</h1>
+<table>
+<tr><th>Handle</th><th>Expires</th><th>Keys</th><th></th></tr>
+{{range .Data.Sessions}}<tr>
+<td><a href="{{pathescape .Handle}}">{{.Handle}}</a></td>
+<td>{{.Expires.Format "2006-01-02 15:04:05 MST"}}{{if .Expired}} (expired){{end}}</td>
+<td>{{len .Keys}}</td>
+<td>
+<form method="post" action="{{pathescape .Handle}}/revoke">{{if $.CSRFField}}<input type="hidden" name="{{$.CSRFField}}" value="{{$.CSRFToken}}">{{end}}<button>Revoke</button></form>
+<form method="post" action="{{pathescape .Handle}}/extend">{{if $.CSRFField}}<input type="hidden" name="{{$.CSRFField}}" value="{{$.CSRFToken}}">{{end}}<input name="duration" value="1h" size="4"><button>Extend</button></form>
+</td>
+</tr>
+{{end}}</table>
+<p>
+{{if gt .Data.Page 1}}<a href="?page={{prev .Data.Page}}">Previous</a>{{end}}
+Page {{.Data.Page}} of {{.Data.Pages}}
+{{if lt .Data.Page .Data.Pages}}<a href="?page={{next .Data.Page}}">Next</a>{{end}}
+</p>
+</body>
+</html>
+`))
+
+var adminSessionTemplate = template.Must(template.New("session").Funcs(adminFuncs).Parse(`<!DOCTYPE html>
+<html>
+<head><title>Session {{.Data.Handle}}</title></head>
+<body>
+<h1>Session {{.Data.Handle}}</h1>
+<p>Expires: {{.Data.Expires.Format "2006-01-02 15:04:05 MST"}}{{if .Data.Expired}} (expired){{end}}</p>
+<h2>Keys</h2>
+<ul>
+{{range .Data.Keys}}<li>{{.}}</li>
+{{end}}</ul>
+<form method="post" action="{{pathescape .Data.Handle}}/revoke">{{if .CSRFField}}<input type="hidden" name="{{.CSRFField}}" value="{{.CSRFToken}}">{{end}}<button>Revoke</button></form>
+<form method="post" action="{{pathescape .Data.Handle}}/extend">{{if .CSRFField}}<input type="hidden" name="{{.CSRFField}}" value="{{.CSRFToken}}">{{end}}<input name="duration" value="1h" size="4"><button>Extend</button></form>
+<p><a href="./">All sessions</a></p>
+</body>
+</html>
+`))
@@ -0,0 +1,201 @@
+package sessions_test
+
+import (
+	"encoding/json"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdminHandler(t *testing.T) {
+	store := NewMapStore()
+
+	ids := make([]string, 0)
+	for i := 0; i < 3; i++ {
+		s, err := sessions.NewDefaultSession()
+		if err != nil {
+			t.Fatalf("Error: creating new session:%s\n", err.Error())
+		}
+		s.Set("Key", i)
+		s.SetExpiry(time.Now().Add(time.Minute))
+		store.Set(s)
+
+		id, _ := s.ID()
+		ids = append(ids, id)
+	}
+
+	// The admin's own session, holding the CSRF secret.
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "ADMINSESSION"
+	si.Timeout = time.Hour
+	si.Store = NewMapStore()
+
+	ah := sessions.NewAdminHandler(store, func(r *http.Request) bool {
+		return r.Header.Get("X-Admin") == "yes"
+	})
+	ah.PageSize = 2
+	ah.CSRF = sessions.NewCSRF(si)
+
+	revoked := make([]string, 0)
+	ah.Hooks = &sessions.Hooks{
+		OnDestroy: func(e sessions.SessionEvent) {
+			revoked = append(revoked, e.ID)
+		},
+	}
+	h := si.GetHandler(ah)
+
+	// Logged in admin.
+	login := sessionstest.NewRequest(t, si, "GET", "/", nil, map[interface{}]interface{}{"User": "admin"})
+	cookie, _ := login.Cookie(si.Cookie.Name)
+
+	serve := func(method, target, token string) *sessionstest.Recorder {
+		r := httptest.NewRequest(method, target, nil)
+		r.Header.Set("X-Admin", "yes")
+		r.AddCookie(cookie)
+		if token != "" {
+			r.Header.Set("X-CSRF-Token", token)
+		}
+
+		w := sessionstest.NewRecorder(si)
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	// Unauthorized
+	w := httptest.NewRecorder()
+	ah.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("Error: expected 403 got %d\n", w.Code)
+	}
+
+	rec := serve("GET", "/?format=json&page=2", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Error: listing sessions got %d\n", rec.Code)
+	}
+
+	page := sessions.AdminPage{}
+	if err := json.NewDecoder(rec.Body).Decode(&page); err != nil {
+		t.Fatalf("Error: decoding listing:%s\n", err.Error())
+	}
+
+	if page.Total != 3 || page.Pages != 2 || page.Page != 2 || len(page.Sessions) != 1 || page.CSRFToken == "" {
+		t.Fatalf("Error: unexpected page %+v\n", page)
+	}
+
+	rec = serve("GET", "/", "")
+	body := rec.Body.String()
+	if rec.Code != http.StatusOK || !strings.Contains(body, "Sessions (3)") || !strings.Contains(body, `name="csrf_token"`) {
+		t.Fatalf("Error: unexpected HTML listing %s\n", body)
+	}
+
+	// Session IDs are never shown.
+	for _, id := range ids {
+		if strings.Contains(body, id) || strings.Contains(body, url.PathEscape(id)) {
+			t.Fatalf("Error: session id %s in listing\n", id)
+		}
+	}
+
+	// Find the handle of ids[0] by its expiry.
+	first, _ := store.Get(ids[0])
+	first.SetExpiry(time.Now().Add(2 * time.Minute))
+	store.Set(first)
+
+	handle := ""
+	for p := 1; p <= page.Pages; p++ {
+		rec = serve("GET", "/?format=json&page="+strconv.Itoa(p), "")
+		listing := sessions.AdminPage{}
+		if err := json.NewDecoder(rec.Body).Decode(&listing); err != nil {
+			t.Fatalf("Error: decoding listing:%s\n", err.Error())
+		}
+
+		for _, as := range listing.Sessions {
+			if as.Expires.Equal(first.Expiry()) {
+				handle = as.Handle
+			}
+		}
+	}
+
+	rec = serve("GET", "/"+url.PathEscape(handle)+"?format=json", "")
+	one := sessions.AdminSession{}
+	if err := json.NewDecoder(rec.Body).Decode(&one); err != nil {
+		t.Fatalf("Error: decoding session:%s\n", err.Error())
+	}
+
+	if one.Handle != handle || len(one.Keys) != 1 || one.Keys[0] != "Key" {
+		t.Fatalf("Error: unexpected session %+v\n", one)
+	}
+
+	// Without a token state changing requests are refused.
+	rec = serve("POST", "/"+url.PathEscape(handle)+"/revoke", "")
+	if rec.Code != http.StatusForbidden || len(store.Records) != 3 {
+		t.Fatalf("Error: expected 403 revoking without a token got %d\n", rec.Code)
+	}
+
+	rec = serve("POST", "/"+url.PathEscape(handle)+"/extend?duration=2h", page.CSRFToken)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Error: extending session got %d\n", rec.Code)
+	}
+
+	s, _ := store.Get(ids[0])
+	if s.Expiry().Before(time.Now().Add(time.Hour)) {
+		t.Fatalf("Error: session not extended, expires %v\n", s.Expiry())
+	}
+
+	rec = serve("POST", "/"+url.PathEscape(handle)+"/revoke", page.CSRFToken)
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("Error: revoking session got %d\n", rec.Code)
+	}
+
+	if len(store.Records) != 2 || len(revoked) != 1 || revoked[0] != ids[0] {
+		t.Fatalf("Error: session not revoked (destroy events %v)\n", revoked)
+	}
+
+	rec = serve("GET", "/"+url.PathEscape(handle), "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Error: expected 404 for revoked session got %d\n", rec.Code)
+	}
+
+	// IDs don't work in place of handles.
+	rec = serve("GET", "/"+url.PathEscape(ids[1]), "")
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("Error: expected 404 for a session id got %d\n", rec.Code)
+	}
+
+	rec = serve("GET", "/"+url.PathEscape(handle)+"/revoke", "")
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("Error: expected 405 got %d\n", rec.Code)
+	}
+}
+
+func TestAdminHandlerWithoutCSRF(t *testing.T) {
+	store := NewMapStore()
+	s, _ := sessions.NewDefaultSession()
+	s.Set("Key", "Value")
+	store.Set(s)
+
+	ah := &sessions.AdminHandler{
+		Store:     store,
+		Authorize: func(*http.Request) bool { return true },
+	}
+
+	w := httptest.NewRecorder()
+	ah.ServeHTTP(w, httptest.NewRequest("GET", "/?format=json", nil))
+
+	page := sessions.AdminPage{}
+	if err := json.NewDecoder(w.Body).Decode(&page); err != nil {
+		t.Fatalf("Error: decoding listing:%s\n", err.Error())
+	}
+
+	// POSTs are refused when no CSRF protection is configured.
+	w = httptest.NewRecorder()
+	ah.ServeHTTP(w, httptest.NewRequest("POST", "/"+url.PathEscape(page.Sessions[0].Handle)+"/revoke", nil))
+	if w.Code != http.StatusForbidden || len(store.Records) != 1 {
+		t.Fatalf("Error: expected 403 got %d\n", w.Code)
+	}
+}
@@ -0,0 +1,116 @@
+package sessions
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+// Archive layout:
+//
+//	header:  "D2GSESS" followed by the version byte.
+//	records: uvarint length followed by the session's GobEncode, repeated.
+//	end:     a zero length record.
+const (
+	archivemagic   string = "D2GSESS"
+	archiveversion byte   = 1
+
+	// Largest record Import will accept.
+	maxarchiverecord uint64 = 64 << 20
+)
+
+var (
+	ErrInvalidArchive = errors.New("sessions: invalid session archive")
+)
+
+// Write every session in store to w, returns the number of sessions written.
+func Export(store SessionStore, w io.Writer) (int, error) {
+	all, err := store.All()
+	if err != nil {
+		return 0, err
+	}
+
+	bw := bufio.NewWriter(w)
+	if _, err := bw.WriteString(archivemagic); err != nil {
+		return 0, err
+	}
+
+	if err := bw.WriteByte(archiveversion); err != nil {
+		return 0, err
+	}
+
+	length := make([]byte, binary.MaxVarintLen64)
+	n := 0
+
+	for _, s := range all {
+		encoded, err := s.GobEncode()
+		if err != nil {
+			return n, err
+		}
+
+		if _, err := bw.Write(length[:binary.PutUvarint(length, uint64(len(encoded)))]); err != nil {
+			return n, err
+		}
+
+		if _, err := bw.Write(encoded); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	if _, err := bw.Write(length[:binary.PutUvarint(length, 0)]); err != nil {
+		return n, err
+	}
+
+	return n, bw.Flush()
+}
+
+// Save every session in an archive written by Export to store, returns the number of sessions saved.
+func Import(store SessionStore, r io.Reader) (int, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(archivemagic)+1)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return 0, ErrInvalidArchive
+	}
+
+	if string(header[:len(archivemagic)]) != archivemagic {
+		return 0, ErrInvalidArchive
+	}
+
+	if header[len(archivemagic)] != archiveversion {
+		return 0, errors.New("sessions: unsupported session archive version")
+	}
+
+	n := 0
+	for {
+		length, err := binary.ReadUvarint(br)
+		if err != nil {
+			return n, ErrInvalidArchive
+		}
+
+		if length == 0 {
+			return n, nil
+		}
+
+		if length > maxarchiverecord {
+			return n, ErrInvalidArchive
+		}
+
+		encoded := make([]byte, length)
+		if _, err := io.ReadFull(br, encoded); err != nil {
+			return n, ErrInvalidArchive
+		}
+
+		s, err := decodeSession(encoded)
+		if err != nil {
+			return n, err
+		}
+
+		if err := store.Set(s); err != nil {
+			return n, err
+		}
+		n++
+	}
+}
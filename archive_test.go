@@ -0,0 +1,67 @@
+package sessions_test
+
+import (
+	"bytes"
+	"github.com/d2g/sessions"
+	"testing"
+	"time"
+)
+
+func TestExportImport(t *testing.T) {
+	src := NewMapStore()
+
+	expiry := time.Now().Add(time.Hour).Round(0)
+	for i := 0; i < 3; i++ {
+		s, err := sessions.NewDefaultSession()
+		if err != nil {
+			t.Fatalf("Error: creating new session:%s\n", err.Error())
+		}
+		s.Set("Key", i)
+		s.SetExpiry(expiry)
+		src.Set(s)
+	}
+
+	buf := new(bytes.Buffer)
+	n, err := sessions.Export(src, buf)
+	if err != nil {
+		t.Fatalf("Error: exporting:%s\n", err.Error())
+	}
+
+	if n != 3 {
+		t.Fatalf("Error: expected 3 sessions exported got %d\n", n)
+	}
+
+	archive := buf.Bytes()
+
+	dst := NewMapStore()
+	n, err = sessions.Import(dst, bytes.NewReader(archive))
+	if err != nil {
+		t.Fatalf("Error: importing:%s\n", err.Error())
+	}
+
+	if n != 3 || len(dst.Records) != 3 {
+		t.Fatalf("Error: expected 3 sessions imported got %d\n", n)
+	}
+
+	for id := range src.Records {
+		s, err := dst.Get(id)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		if !s.Expiry().Equal(expiry) {
+			t.Fatalf("Error: expiry got %v wanted %v\n", s.Expiry(), expiry)
+		}
+	}
+
+	// Truncated archives are rejected.
+	_, err = sessions.Import(NewMapStore(), bytes.NewReader(archive[:len(archive)-1]))
+	if err != sessions.ErrInvalidArchive {
+		t.Fatalf("Error: expected invalid archive error got %v\n", err)
+	}
+
+	_, err = sessions.Import(NewMapStore(), bytes.NewReader([]byte("Not an archive")))
+	if err != sessions.ErrInvalidArchive {
+		t.Fatalf("Error: expected invalid archive error got %v\n", err)
+	}
+}
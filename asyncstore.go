@@ -0,0 +1,229 @@
+package sessions
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrStoreClosed = errors.New("sessions: store closed")
+)
+
+// AsyncStore queues saves and writes them to the inner store in the background.
+//
+// Saves of the same session are coalesced so only the latest version is written,
+// and if the inner store is a BatchStore each batch is written with a single SetAll.
+// Sessions waiting to be written are returned by Get so a request always sees the last save.
+// Close must be called on shutdown to write any queued sessions.
+type AsyncStore struct {
+	store     SessionStore
+	batchSize int
+
+	// Called with errors from background writes, the sessions stay queued and are retried.
+	OnError func(error)
+
+	mu      sync.Mutex
+	pending map[string]*asyncEntry
+	closed  bool
+
+	// Held while writing to the inner store.
+	writing sync.Mutex
+
+	kick chan struct{}
+	quit chan struct{}
+	done chan struct{}
+}
+
+type asyncEntry struct {
+	encoded []byte
+}
+
+// Create an AsyncStore in front of store.
+// Queued sessions are written every interval (a second if it's not positive) or as soon as batchSize sessions are waiting.
+func NewAsyncStore(store SessionStore, interval time.Duration, batchSize int) *AsyncStore {
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	t := &AsyncStore{
+		store:     store,
+		batchSize: batchSize,
+		pending:   make(map[string]*asyncEntry),
+		kick:      make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go t.run(interval)
+	return t
+}
+
+func (t *AsyncStore) Get(id string) (Session, error) {
+	t.mu.Lock()
+	entry, ok := t.pending[id]
+	t.mu.Unlock()
+
+	if ok {
+		return decodeSession(entry.encoded)
+	}
+
+	return t.store.Get(id)
+}
+
+// Queue the session to be saved.
+func (t *AsyncStore) Set(s Session) error {
+	id, err := s.ID()
+	if err != nil {
+		return err
+	}
+
+	encoded, err := s.GobEncode()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed {
+		return ErrStoreClosed
+	}
+
+	t.pending[id] = &asyncEntry{encoded}
+
+	if len(t.pending) >= t.batchSize {
+		select {
+		case t.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (t *AsyncStore) Delete(id string) error {
+	// Stop a write in progress bringing the session back.
+	t.writing.Lock()
+	defer t.writing.Unlock()
+
+	t.mu.Lock()
+	delete(t.pending, id)
+	t.mu.Unlock()
+
+	return t.store.Delete(id)
+}
+
+// All sessions in the inner store, queued sessions are written first.
+func (t *AsyncStore) All() ([]Session, error) {
+	if err := t.Flush(); err != nil {
+		return nil, err
+	}
+
+	return t.store.All()
+}
+
+// Write the sessions queued when Flush is called to the inner store.
+// Sessions saved while it's writing are left for the next flush, so Flush returns under steady saves.
+func (t *AsyncStore) Flush() error {
+	t.writing.Lock()
+	defer t.writing.Unlock()
+
+	t.mu.Lock()
+	ids := make([]string, 0, len(t.pending))
+	entries := make([]*asyncEntry, 0, len(t.pending))
+	for id, entry := range t.pending {
+		ids = append(ids, id)
+		entries = append(entries, entry)
+	}
+	t.mu.Unlock()
+
+	for start := 0; start < len(ids); start += t.batchSize {
+		end := start + t.batchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		if err := t.write(ids[start:end], entries[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Stop the background writer and write all queued sessions.
+// Saves after Close return ErrStoreClosed.
+func (t *AsyncStore) Close() error {
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil
+	}
+	t.closed = true
+	t.mu.Unlock()
+
+	close(t.quit)
+	<-t.done
+
+	return t.Flush()
+}
+
+// Must be called with writing held.
+func (t *AsyncStore) write(ids []string, entries []*asyncEntry) error {
+	batch := make([]Session, 0, len(entries))
+	for _, entry := range entries {
+		s, err := decodeSession(entry.encoded)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, s)
+	}
+
+	var err error
+	if bs, ok := t.store.(BatchStore); ok {
+		err = bs.SetAll(batch)
+	} else {
+		for _, s := range batch {
+			if err = t.store.Set(s); err != nil {
+				break
+			}
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	// Sessions saved again while we were writing stay queued.
+	t.mu.Lock()
+	for i, id := range ids {
+		if t.pending[id] == entries[i] {
+			delete(t.pending, id)
+		}
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+func (t *AsyncStore) run(interval time.Duration) {
+	defer close(t.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.quit:
+			return
+		case <-ticker.C:
+		case <-t.kick:
+		}
+
+		if err := t.Flush(); err != nil && t.OnError != nil {
+			t.OnError(err)
+		}
+	}
+}
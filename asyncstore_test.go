@@ -0,0 +1,177 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"testing"
+	"time"
+)
+
+// Counts the batches written to a MapStore.
+type BatchMapStore struct {
+	*MapStore
+	Batches int
+}
+
+func (t *BatchMapStore) SetAll(ss []sessions.Session) error {
+	t.Batches++
+	for _, s := range ss {
+		if err := t.MapStore.Set(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestAsyncStore(t *testing.T) {
+	inner := &BatchMapStore{MapStore: NewMapStore()}
+	as := sessions.NewAsyncStore(inner, time.Hour, 10)
+
+	s, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	id, _ := s.ID()
+
+	s.Set("Key", "First")
+	err = as.Set(s)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	s.Set("Key", "Second")
+	err = as.Set(s)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	inner.Lock()
+	if len(inner.Records) != 0 {
+		t.Fatalf("Error: session written before flush\n")
+	}
+	inner.Unlock()
+
+	// Queued sessions are visible.
+	queued, err := as.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if v, _ := queued.Get("Key"); v != "Second" {
+		t.Fatalf("Error: expected \"Second\" received \"%v\"\n", v)
+	}
+
+	err = as.Close()
+	if err != nil {
+		t.Fatalf("Error: closing store:%s\n", err.Error())
+	}
+
+	if inner.Batches != 1 {
+		t.Fatalf("Error: expected 1 batch got %d\n", inner.Batches)
+	}
+
+	saved, err := inner.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if v, _ := saved.Get("Key"); v != "Second" {
+		t.Fatalf("Error: expected \"Second\" received \"%v\"\n", v)
+	}
+
+	err = as.Set(s)
+	if err != sessions.ErrStoreClosed {
+		t.Fatalf("Error: expected store closed error got %v\n", err)
+	}
+}
+
+func TestAsyncStoreBatchSize(t *testing.T) {
+	inner := &BatchMapStore{MapStore: NewMapStore()}
+	as := sessions.NewAsyncStore(inner, time.Hour, 2)
+	defer as.Close()
+
+	for i := 0; i < 2; i++ {
+		s, _ := sessions.NewDefaultSession()
+		if err := as.Set(s); err != nil {
+			t.Fatalf("Error: saving session:%s\n", err.Error())
+		}
+	}
+
+	// A full batch is written without waiting for the interval.
+	for i := 0; i < 100; i++ {
+		inner.Lock()
+		n := len(inner.Records)
+		inner.Unlock()
+
+		if n == 2 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("Error: full batch not written\n")
+}
+
+func TestAsyncStoreConformance(t *testing.T) {
+	sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+		as := sessions.NewAsyncStore(NewMapStore(), time.Millisecond, 10)
+		t.Cleanup(func() {
+			as.Close()
+		})
+		return as
+	})
+}
+
+func TestAsyncStoreZeroInterval(t *testing.T) {
+	as := sessions.NewAsyncStore(NewMapStore(), 0, 0)
+	if err := as.Close(); err != nil {
+		t.Fatalf("Error: closing:%s\n", err.Error())
+	}
+}
+
+func TestAsyncStoreFlushSnapshot(t *testing.T) {
+	inner := NewGatedStore()
+	as := sessions.NewAsyncStore(inner, time.Hour, 100)
+
+	first, _ := sessions.NewDefaultSession()
+	if err := as.Set(first); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	inner.gateSet = true
+	done := make(chan error, 1)
+	go func() {
+		done <- as.Flush()
+	}()
+	<-inner.inside
+
+	// Saved while the flush is writing, it's left for the next flush.
+	second, _ := sessions.NewDefaultSession()
+	secondid, _ := second.ID()
+	if err := as.Set(second); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+	close(inner.gate)
+
+	if err := <-done; err != nil {
+		t.Fatalf("Error: flushing:%s\n", err.Error())
+	}
+
+	inner.Lock()
+	_, ok := inner.Records[secondid]
+	n := len(inner.Records)
+	inner.Unlock()
+
+	if ok || n != 1 {
+		t.Fatalf("Error: expected only the first session written got %d\n", n)
+	}
+
+	inner.gateSet = false
+	if err := as.Close(); err != nil {
+		t.Fatalf("Error: closing:%s\n", err.Error())
+	}
+
+	if _, ok := inner.Records[secondid]; !ok {
+		t.Fatalf("Error: queued session not written on close\n")
+	}
+}
@@ -5,17 +5,151 @@ import (
 	"encoding/gob"
 	"github.com/boltdb/bolt"
 	"github.com/d2g/sessions"
-	"log"
+	"io"
+	"log/slog"
+	"os"
+	"time"
 )
 
 type BoltStore struct {
 	DB *bolt.DB
+
+	// Where messages are logged, defaults to slog.Default().
+	Logger *slog.Logger
+
+	// The bucket sessions are stored in, the indexes are in buckets alongside it.
+	bucketname string
+}
+
+type Options struct {
+	// The bucket sessions are stored in, defaults to "sessions".
+	// Applications sharing a Bolt file each need their own bucket.
+	Bucket string
+
+	// How long Open waits for the file lock, 0 waits forever.
+	Timeout time.Duration
+
+	// The permissions Open creates the file with, defaults to 0600.
+	Mode os.FileMode
+
+	// Open the file read only, it isn't created and nothing is written to it (not even the buckets).
+	// Several read only stores can have the file open at once, Set and Delete return bolt.ErrDatabaseReadOnly.
+	ReadOnly bool
+
+	// Where messages are logged, defaults to slog.Default().
+	Logger *slog.Logger
 }
 
 const (
-	bucketname string = "sessions"
+	defaultbucketname string = "sessions"
+
+	// User index, a nested bucket per user holding the IDs of the user's sessions.
+	usersuffix string = ".users"
+
+	// Reverse of the user index, session ID to user.
+	sessionusersuffix string = ".sessionusers"
+
+	// Records that couldn't be decoded.
+	quarantinesuffix string = ".quarantine"
+
+	// Expiry index, expiry and session ID.
+	expirysuffix string = ".expiry"
+
+	// Reverse of the expiry index, session ID to expiry index key.
+	sessionexpirysuffix string = ".sessionexpiry"
 )
 
+// Open the Bolt file at path and create a store in it.
+// Close the store to close the file.
+func Open(path string, opts *Options) (*BoltStore, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	mode := opts.Mode
+	if mode == 0 {
+		mode = 0600
+	}
+
+	//Bolt creates missing files even when it's read only (and then fails to initialise them).
+	if opts.ReadOnly {
+		if _, err := os.Stat(path); err != nil {
+			return nil, err
+		}
+	}
+
+	db, err := bolt.Open(path, mode, &bolt.Options{Timeout: opts.Timeout, ReadOnly: opts.ReadOnly})
+	if err != nil {
+		return nil, err
+	}
+
+	store, err := New(db, opts)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// Create a store in an already open Bolt database, the buckets are created up front unless it's read only.
+// opts.Timeout, opts.Mode and opts.ReadOnly aren't used.
+func New(db *bolt.DB, opts *Options) (*BoltStore, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	b := &BoltStore{
+		DB:         db,
+		Logger:     opts.Logger,
+		bucketname: opts.Bucket,
+	}
+
+	//Reads cope with missing buckets.
+	if db.IsReadOnly() {
+		return b, nil
+	}
+
+	err := db.Update(func(tx *bolt.Tx) error {
+		if tx.Bucket(b.bucket(expirysuffix)) == nil {
+			if err := b.buildExpiryIndex(tx); err != nil {
+				return err
+			}
+		}
+
+		for _, suffix := range []string{"", usersuffix, sessionusersuffix, expirysuffix, sessionexpirysuffix} {
+			if _, err := tx.CreateBucketIfNotExists(b.bucket(suffix)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Close the underlying Bolt database.
+func (b *BoltStore) Close() error {
+	return b.DB.Close()
+}
+
+func (b *BoltStore) bucket(suffix string) []byte {
+	if b.bucketname == "" {
+		return []byte(defaultbucketname + suffix)
+	}
+	return []byte(b.bucketname + suffix)
+}
+
+func (b *BoltStore) logger() *slog.Logger {
+	if b.Logger != nil {
+		return b.Logger
+	}
+	return slog.Default()
+}
+
 func (b *BoltStore) Get(id string) (sessions.Session, error) {
 	var err error
 
@@ -28,18 +162,26 @@ func (b *BoltStore) Get(id string) (sessions.Session, error) {
 		var bo []byte
 
 		err = b.DB.View(func(tx *bolt.Tx) error {
-			bkt := tx.Bucket([]byte(bucketname))
+			bkt := tx.Bucket(b.bucket(""))
 			if bkt == nil {
 				return nil
 			}
 
-			bo = bkt.Get([]byte(id))
+			//Values are only valid until the transaction ends.
+			if v := bkt.Get([]byte(id)); v != nil {
+				bo = append([]byte{}, v...)
+			}
 			return nil
 		})
 		if err != nil {
 			return s, err
 		}
 
+		//Not found, hand back the new session.
+		if bo == nil {
+			return s, nil
+		}
+
 		dec := gob.NewDecoder(bytes.NewBuffer(bo))
 		if err := dec.Decode(&s); err != nil {
 			return s, err
@@ -50,6 +192,28 @@ func (b *BoltStore) Get(id string) (sessions.Session, error) {
 }
 
 func (b *BoltStore) Set(s sessions.Session) error {
+	return b.SetAll([]sessions.Session{s})
+}
+
+// Save the sessions in a single transaction.
+func (b *BoltStore) SetAll(ss []sessions.Session) error {
+	return b.DB.Update(func(tx *bolt.Tx) error {
+		bkt, err := tx.CreateBucketIfNotExists(b.bucket(""))
+		if err != nil {
+			return err
+		}
+
+		for _, s := range ss {
+			if err := b.put(tx, bkt, s); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (b *BoltStore) put(tx *bolt.Tx, bkt *bolt.Bucket, s sessions.Session) error {
 	sessionid, err := s.ID()
 	if err != nil {
 		return err
@@ -61,62 +225,197 @@ func (b *BoltStore) Set(s sessions.Session) error {
 		return err
 	}
 
-	err = b.DB.Update(func(tx *bolt.Tx) error {
-		bkt, err := tx.CreateBucketIfNotExists([]byte(bucketname))
-		if err != nil {
-			return err
-		}
+	err = bkt.Put([]byte(sessionid), buf.Bytes())
+	if err != nil {
+		return err
+	}
 
-		err = bkt.Put([]byte(sessionid), buf.Bytes())
+	user, err := sessions.SessionUser(s)
+	if err != nil {
 		return err
-	})
+	}
 
-	return err
+	err = b.indexUser(tx, sessionid, user)
+	if err != nil {
+		return err
+	}
+
+	return b.indexExpiry(tx, sessionid, s.Expiry())
 }
 
 func (b *BoltStore) Delete(id string) error {
 	err := b.DB.Update(func(tx *bolt.Tx) error {
-		bkt, err := tx.CreateBucketIfNotExists([]byte(bucketname))
+		bkt, err := tx.CreateBucketIfNotExists(b.bucket(""))
 		if err != nil {
 			return err
 		}
 		err = bkt.Delete([]byte(id))
-		return err
+		if err != nil {
+			return err
+		}
+
+		err = b.indexUser(tx, id, "")
+		if err != nil {
+			return err
+		}
+
+		return b.unindexExpiry(tx, id)
 	})
 
 	if err != nil {
-		log.Println("Error: Deleting Session from Datastore")
+		b.logger().Error("deleting session", "session_id", id, "error", err)
 	}
 
 	return err
 }
 
-func (b *BoltStore) All() ([]sessions.Session, error) {
-	s := make([]sessions.Session, 0, 0)
+// Write a consistent copy of the whole database to w while the store stays in use.
+func (b *BoltStore) Backup(w io.Writer) (int64, error) {
+	var n int64
+
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+
+	return n, err
+}
+
+// The IDs of the sessions bound to the user.
+func (b *BoltStore) UserSessions(user string) ([]string, error) {
+	ids := make([]string, 0)
 
 	err := b.DB.View(func(tx *bolt.Tx) error {
-		bkt := tx.Bucket([]byte(bucketname))
-		c := bkt.Cursor()
+		users := tx.Bucket(b.bucket(usersuffix))
+		if users == nil {
+			return nil
+		}
+
+		bkt := users.Bucket([]byte(user))
+		if bkt == nil {
+			return nil
+		}
 
-		for k, v := c.First(); k != nil; k, v = c.Next() {
+		return bkt.ForEach(func(k, v []byte) error {
+			ids = append(ids, string(k))
+			return nil
+		})
+	})
+
+	return ids, err
+}
 
-			session, err := sessions.NewDefaultSession()
-			if err != nil {
+// Move the session to the user's index, an empty user removes it from the index.
+func (b *BoltStore) indexUser(tx *bolt.Tx, id string, user string) error {
+	sessionusers, err := tx.CreateBucketIfNotExists(b.bucket(sessionusersuffix))
+	if err != nil {
+		return err
+	}
+
+	users, err := tx.CreateBucketIfNotExists(b.bucket(usersuffix))
+	if err != nil {
+		return err
+	}
+
+	previous := string(sessionusers.Get([]byte(id)))
+	if previous == user {
+		return nil
+	}
+
+	if previous != "" {
+		if bkt := users.Bucket([]byte(previous)); bkt != nil {
+			if err := bkt.Delete([]byte(id)); err != nil {
 				return err
 			}
 
-			dec := gob.NewDecoder(bytes.NewBuffer(v))
-			if err := dec.Decode(&session); err != nil {
-				//Broken Session try and remove it.
-				b.Delete(string(k))
-				log.Panicf("Warning: Deleting Broken Session \"%s\"\n", string(v))
-				continue
+			if k, _ := bkt.Cursor().First(); k == nil {
+				if err := users.DeleteBucket([]byte(previous)); err != nil {
+					return err
+				}
 			}
-			s = append(s, session)
 		}
+	}
 
-		return nil
+	if user == "" {
+		return sessionusers.Delete([]byte(id))
+	}
+
+	bkt, err := users.CreateBucketIfNotExists([]byte(user))
+	if err != nil {
+		return err
+	}
+
+	if err := bkt.Put([]byte(id), []byte{}); err != nil {
+		return err
+	}
+
+	return sessionusers.Put([]byte(id), []byte(user))
+}
+
+// All the sessions in the store, records that can't be decoded are skipped and quarantined.
+// A read only store can't move them, they're skipped and logged.
+func (b *BoltStore) All() ([]sessions.Session, error) {
+	s := make([]sessions.Session, 0, 0)
+	corrupt := make([]sessions.QuarantinedRecord, 0)
+
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		s, corrupt, err = b.scan(tx)
+		return err
+	})
+	if err != nil || len(corrupt) == 0 {
+		return s, err
+	}
+
+	if b.DB.IsReadOnly() {
+		for _, record := range corrupt {
+			b.logger().Warn("skipping corrupt session", "session_id", record.ID, "error", record.Error)
+		}
+		return s, nil
+	}
+
+	//Quarantined once we're out of the read only transaction.
+	err = b.DB.Update(func(tx *bolt.Tx) error {
+		_, err := b.quarantine(tx, corrupt)
+		return err
 	})
 
 	return s, err
 }
+
+// Decode every session, returning the records that couldn't be decoded separately.
+func (b *BoltStore) scan(tx *bolt.Tx) ([]sessions.Session, []sessions.QuarantinedRecord, error) {
+	s := make([]sessions.Session, 0, 0)
+	corrupt := make([]sessions.QuarantinedRecord, 0)
+
+	bkt := tx.Bucket(b.bucket(""))
+	if bkt == nil {
+		//Nothing has been saved yet.
+		return s, corrupt, nil
+	}
+	c := bkt.Cursor()
+
+	for k, v := c.First(); k != nil; k, v = c.Next() {
+
+		session, err := sessions.NewDefaultSession()
+		if err != nil {
+			return s, corrupt, err
+		}
+
+		dec := gob.NewDecoder(bytes.NewBuffer(v))
+		if err := dec.Decode(&session); err != nil {
+			//Broken Session.
+			corrupt = append(corrupt, sessions.QuarantinedRecord{
+				ID:    string(k),
+				Data:  append([]byte{}, v...),
+				Error: err.Error(),
+				Time:  time.Now(),
+			})
+			continue
+		}
+		s = append(s, session)
+	}
+
+	return s, corrupt, nil
+}
@@ -0,0 +1,279 @@
+package boltsessionstore_test
+
+import (
+	"bytes"
+	"github.com/boltdb/bolt"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/boltsessionstore"
+	"github.com/d2g/sessions/sessionstest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBoltStoreConformance(t *testing.T) {
+	dir := t.TempDir()
+	n := 0
+
+	sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+		n++
+		store, err := boltsessionstore.Open(filepath.Join(dir, strconv.Itoa(n)+".db"), nil)
+		if err != nil {
+			t.Fatalf("Error: opening store:%s\n", err.Error())
+		}
+		t.Cleanup(func() {
+			store.Close()
+		})
+		return store
+	})
+}
+
+func TestBoltStoreBucket(t *testing.T) {
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "sessions.db"), 0600, nil)
+	if err != nil {
+		t.Fatalf("Error: opening database:%s\n", err.Error())
+	}
+	defer db.Close()
+
+	a, err := boltsessionstore.New(db, &boltsessionstore.Options{Bucket: "a"})
+	if err != nil {
+		t.Fatalf("Error: creating store:%s\n", err.Error())
+	}
+
+	b, err := boltsessionstore.New(db, &boltsessionstore.Options{Bucket: "b"})
+	if err != nil {
+		t.Fatalf("Error: creating store:%s\n", err.Error())
+	}
+
+	s, _ := sessions.NewDefaultSession()
+	s.Set("Key", "Value")
+	sessions.BindUser(s, "alice")
+	id, _ := s.ID()
+
+	if err := a.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	all, err := b.All()
+	if err != nil || len(all) != 0 {
+		t.Fatalf("Error: expected no sessions in bucket b got %d (%v)\n", len(all), err)
+	}
+
+	got, err := b.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if v, _ := got.Get("Key"); v != nil {
+		t.Fatalf("Error: session from bucket a found in bucket b\n")
+	}
+
+	ids, err := b.UserSessions("alice")
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("Error: expected no user sessions in bucket b got %v (%v)\n", ids, err)
+	}
+
+	// Deleting from b leaves a alone.
+	if err := b.Delete(id); err != nil {
+		t.Fatalf("Error: deleting session:%s\n", err.Error())
+	}
+
+	all, err = a.All()
+	if err != nil || len(all) != 1 {
+		t.Fatalf("Error: expected 1 session in bucket a got %d (%v)\n", len(all), err)
+	}
+
+	ids, err = a.UserSessions("alice")
+	if err != nil || len(ids) != 1 || ids[0] != id {
+		t.Fatalf("Error: expected [%s] got %v (%v)\n", id, ids, err)
+	}
+}
+
+func TestBoltStoreOpenOptions(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, mode := range []os.FileMode{0, 0640} {
+		path := filepath.Join(dir, strconv.FormatUint(uint64(mode), 8)+".db")
+		store, err := boltsessionstore.Open(path, &boltsessionstore.Options{Mode: mode})
+		if err != nil {
+			t.Fatalf("Error: opening store:%s\n", err.Error())
+		}
+		store.Close()
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Error: reading file info:%s\n", err.Error())
+		}
+
+		expected := mode
+		if expected == 0 {
+			expected = 0600
+		}
+
+		if info.Mode().Perm() != expected {
+			t.Fatalf("Error: expected mode %v got %v\n", expected, info.Mode().Perm())
+		}
+	}
+
+	// The file is locked while it's open, a second Open gives up after the timeout.
+	path := filepath.Join(dir, "locked.db")
+	store, err := boltsessionstore.Open(path, nil)
+	if err != nil {
+		t.Fatalf("Error: opening store:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	start := time.Now()
+	_, err = boltsessionstore.Open(path, &boltsessionstore.Options{Timeout: 50 * time.Millisecond})
+	if err != bolt.ErrTimeout {
+		t.Fatalf("Error: expected timeout error got %v\n", err)
+	}
+
+	if time.Since(start) > 5*time.Second {
+		t.Fatalf("Error: Open waited %v\n", time.Since(start))
+	}
+}
+
+func TestBoltStoreUserSessions(t *testing.T) {
+	store, err := boltsessionstore.Open(filepath.Join(t.TempDir(), "sessions.db"), nil)
+	if err != nil {
+		t.Fatalf("Error: opening store:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	var _ sessions.UserIndex = store
+
+	s, _ := sessions.NewDefaultSession()
+	id, _ := s.ID()
+
+	users := func(user string) []string {
+		ids, err := store.UserSessions(user)
+		if err != nil {
+			t.Fatalf("Error: getting user sessions:%s\n", err.Error())
+		}
+		return ids
+	}
+
+	sessions.BindUser(s, "alice")
+	store.Set(s)
+
+	if ids := users("alice"); len(ids) != 1 || ids[0] != id {
+		t.Fatalf("Error: expected [%s] for alice got %v\n", id, ids)
+	}
+
+	// Binding to another user moves the session.
+	sessions.BindUser(s, "bob")
+	store.Set(s)
+
+	if ids := users("alice"); len(ids) != 0 {
+		t.Fatalf("Error: expected no sessions for alice got %v\n", ids)
+	}
+
+	if ids := users("bob"); len(ids) != 1 || ids[0] != id {
+		t.Fatalf("Error: expected [%s] for bob got %v\n", id, ids)
+	}
+
+	// Unbinding removes it.
+	sessions.BindUser(s, "")
+	store.Set(s)
+
+	if ids := users("bob"); len(ids) != 0 {
+		t.Fatalf("Error: expected no sessions for bob got %v\n", ids)
+	}
+
+	sessions.BindUser(s, "bob")
+	store.Set(s)
+	store.Delete(id)
+
+	if ids := users("bob"); len(ids) != 0 {
+		t.Fatalf("Error: expected no sessions for bob after delete got %v\n", ids)
+	}
+}
+
+func TestBoltStoreBackup(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := boltsessionstore.Open(filepath.Join(dir, "sessions.db"), nil)
+	if err != nil {
+		t.Fatalf("Error: opening store:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	s, _ := sessions.NewDefaultSession()
+	s.Set("Key", "Value")
+	sessions.BindUser(s, "alice")
+	id, _ := s.ID()
+	store.Set(s)
+
+	buf := new(bytes.Buffer)
+	n, err := store.Backup(buf)
+	if err != nil {
+		t.Fatalf("Error: backing up:%s\n", err.Error())
+	}
+
+	if n != int64(buf.Len()) {
+		t.Fatalf("Error: expected %d bytes got %d\n", buf.Len(), n)
+	}
+
+	path := filepath.Join(dir, "backup.db")
+	if err := os.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatalf("Error: writing backup:%s\n", err.Error())
+	}
+
+	restored, err := boltsessionstore.Open(path, nil)
+	if err != nil {
+		t.Fatalf("Error: opening backup:%s\n", err.Error())
+	}
+	defer restored.Close()
+
+	got, err := restored.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if v, _ := got.Get("Key"); v != "Value" {
+		t.Fatalf("Error: expected \"Value\" got \"%v\"\n", v)
+	}
+
+	ids, err := restored.UserSessions("alice")
+	if err != nil || len(ids) != 1 || ids[0] != id {
+		t.Fatalf("Error: expected [%s] got %v (%v)\n", id, ids, err)
+	}
+}
+
+func TestBoltStoreReadOnly(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	if _, err := boltsessionstore.Open(path, &boltsessionstore.Options{ReadOnly: true}); err == nil {
+		t.Fatalf("Error: expected opening a missing file read only to fail\n")
+	}
+
+	// A file the store has never written its buckets to.
+	db, err := bolt.Open(path, 0600, nil)
+	if err != nil {
+		t.Fatalf("Error: creating database:%s\n", err.Error())
+	}
+	db.Close()
+
+	store, err := boltsessionstore.Open(path, &boltsessionstore.Options{ReadOnly: true})
+	if err != nil {
+		t.Fatalf("Error: opening store read only:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	all, err := store.All()
+	if err != nil || len(all) != 0 {
+		t.Fatalf("Error: expected no sessions got %d (%v)\n", len(all), err)
+	}
+
+	s, _ := sessions.NewDefaultSession()
+	if err := store.Set(s); err != bolt.ErrDatabaseReadOnly {
+		t.Fatalf("Error: expected read only error got %v\n", err)
+	}
+
+	if ids, err := store.UserSessions("alice"); err != nil || len(ids) != 0 {
+		t.Fatalf("Error: expected no user sessions got %v (%v)\n", ids, err)
+	}
+}
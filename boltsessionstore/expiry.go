@@ -0,0 +1,128 @@
+package boltsessionstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"github.com/boltdb/bolt"
+	"github.com/d2g/sessions"
+	"time"
+)
+
+// The expiry index is keyed by expiry then session ID so expired sessions can be found with a range scan.
+// Key layout: seconds since the epoch (sign bit flipped so earlier times sort first), nanoseconds, session ID.
+func expiryKey(expiry time.Time, id string) []byte {
+	key := make([]byte, 12, 12+len(id))
+	binary.BigEndian.PutUint64(key[0:8], uint64(expiry.Unix())^(1<<63))
+	binary.BigEndian.PutUint32(key[8:12], uint32(expiry.Nanosecond()))
+	return append(key, id...)
+}
+
+// Move the session to its new position in the expiry index.
+func (b *BoltStore) indexExpiry(tx *bolt.Tx, id string, expiry time.Time) error {
+	if err := b.unindexExpiry(tx, id); err != nil {
+		return err
+	}
+
+	expiries, err := tx.CreateBucketIfNotExists(b.bucket(expirysuffix))
+	if err != nil {
+		return err
+	}
+
+	sessionexpiries, err := tx.CreateBucketIfNotExists(b.bucket(sessionexpirysuffix))
+	if err != nil {
+		return err
+	}
+
+	key := expiryKey(expiry, id)
+	if err := expiries.Put(key, []byte{}); err != nil {
+		return err
+	}
+
+	return sessionexpiries.Put([]byte(id), key)
+}
+
+// Remove the session from the expiry index.
+func (b *BoltStore) unindexExpiry(tx *bolt.Tx, id string) error {
+	sessionexpiries := tx.Bucket(b.bucket(sessionexpirysuffix))
+	if sessionexpiries == nil {
+		return nil
+	}
+
+	key := sessionexpiries.Get([]byte(id))
+	if key == nil {
+		return nil
+	}
+
+	if expiries := tx.Bucket(b.bucket(expirysuffix)); expiries != nil {
+		if err := expiries.Delete(key); err != nil {
+			return err
+		}
+	}
+
+	return sessionexpiries.Delete([]byte(id))
+}
+
+// Index the sessions saved before the store kept an expiry index.
+// Must be called in the transaction that creates the index.
+func (b *BoltStore) buildExpiryIndex(tx *bolt.Tx) error {
+	bkt := tx.Bucket(b.bucket(""))
+	if bkt == nil {
+		return nil
+	}
+
+	return bkt.ForEach(func(k, v []byte) error {
+		session, err := sessions.NewDefaultSession()
+		if err != nil {
+			return err
+		}
+
+		dec := gob.NewDecoder(bytes.NewBuffer(v))
+		if err := dec.Decode(&session); err != nil {
+			//Left for QuarantineCorrupt.
+			return nil
+		}
+
+		return b.indexExpiry(tx, string(k), session.Expiry())
+	})
+}
+
+// Delete every session that expired before the given time, returns the IDs of the deleted sessions.
+// The sessions are found from the expiry index so nothing is decoded.
+func (b *BoltStore) DeleteExpired(before time.Time) ([]string, error) {
+	ids := make([]string, 0)
+
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		expiries := tx.Bucket(b.bucket(expirysuffix))
+		if expiries == nil {
+			return nil
+		}
+
+		end := expiryKey(before, "")
+		c := expiries.Cursor()
+		for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+			ids = append(ids, string(k[12:]))
+		}
+
+		bkt := tx.Bucket(b.bucket(""))
+		for _, id := range ids {
+			if bkt != nil {
+				if err := bkt.Delete([]byte(id)); err != nil {
+					return err
+				}
+			}
+
+			if err := b.indexUser(tx, id, ""); err != nil {
+				return err
+			}
+
+			if err := b.unindexExpiry(tx, id); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return ids, err
+}
@@ -0,0 +1,174 @@
+package boltsessionstore_test
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/boltsessionstore"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// Save a session expiring at expiry, returns its ID.
+func saveExpiring(t *testing.T, store *boltsessionstore.BoltStore, expiry time.Time) string {
+	s, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	s.Set("Key", "Value")
+	s.SetExpiry(expiry)
+
+	if err := store.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	id, _ := s.ID()
+	return id
+}
+
+func deleteExpired(t *testing.T, store *boltsessionstore.BoltStore, before time.Time) []string {
+	ids, err := store.DeleteExpired(before)
+	if err != nil {
+		t.Fatalf("Error: deleting expired sessions:%s\n", err.Error())
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func sameIDs(a []string, b ...string) bool {
+	sort.Strings(b)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBoltStoreDeleteExpired(t *testing.T) {
+	store, err := boltsessionstore.Open(filepath.Join(t.TempDir(), "sessions.db"), nil)
+	if err != nil {
+		t.Fatalf("Error: opening store:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	var _ sessions.ExpiredDeleter = store
+
+	now := time.Date(2015, 6, 1, 12, 0, 0, 500, time.UTC)
+
+	zero := saveExpiring(t, store, time.Time{})
+	old := saveExpiring(t, store, time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC))
+	before1970 := saveExpiring(t, store, time.Date(1969, 12, 31, 23, 59, 59, 999999999, time.UTC))
+	justbefore := saveExpiring(t, store, now.Add(-time.Nanosecond))
+	at := saveExpiring(t, store, now)
+	after := saveExpiring(t, store, now.Add(time.Hour))
+
+	// Negative times sort before positive ones.
+	if ids := deleteExpired(t, store, time.Date(1965, 1, 1, 0, 0, 0, 0, time.UTC)); !sameIDs(ids, zero, old) {
+		t.Fatalf("Error: expected the zero and 1960 sessions deleted got %v\n", ids)
+	}
+
+	// The bound is exclusive, down to the nanosecond.
+	if ids := deleteExpired(t, store, now); !sameIDs(ids, before1970, justbefore) {
+		t.Fatalf("Error: expected the sessions before %v deleted got %v\n", now, ids)
+	}
+
+	if ids := deleteExpired(t, store, now); len(ids) != 0 {
+		t.Fatalf("Error: expected nothing deleted twice got %v\n", ids)
+	}
+
+	all, err := store.All()
+	if err != nil || len(all) != 2 {
+		t.Fatalf("Error: expected 2 sessions left got %d (%v)\n", len(all), err)
+	}
+
+	if ids := deleteExpired(t, store, now.Add(2*time.Hour)); !sameIDs(ids, at, after) {
+		t.Fatalf("Error: expected the remaining sessions deleted got %v\n", ids)
+	}
+}
+
+func TestBoltStoreExpiryReindex(t *testing.T) {
+	store, err := boltsessionstore.Open(filepath.Join(t.TempDir(), "sessions.db"), nil)
+	if err != nil {
+		t.Fatalf("Error: opening store:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	now := time.Now()
+	id := saveExpiring(t, store, now.Add(-time.Hour))
+
+	// Extended, the old expiry is no longer indexed.
+	s, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+	sessions.BindUser(s, "alice")
+	s.SetExpiry(now.Add(time.Hour))
+	store.Set(s)
+
+	if ids := deleteExpired(t, store, now); len(ids) != 0 {
+		t.Fatalf("Error: expected extended session kept got %v\n", ids)
+	}
+
+	if ids := deleteExpired(t, store, now.Add(2*time.Hour)); !sameIDs(ids, id) {
+		t.Fatalf("Error: expected [%s] deleted got %v\n", id, ids)
+	}
+
+	// Deleted sessions leave the user index too.
+	ids, err := store.UserSessions("alice")
+	if err != nil || len(ids) != 0 {
+		t.Fatalf("Error: expected no user sessions got %v (%v)\n", ids, err)
+	}
+
+	// Deleted sessions leave the expiry index.
+	id = saveExpiring(t, store, now.Add(-time.Hour))
+	store.Delete(id)
+
+	if ids := deleteExpired(t, store, now); len(ids) != 0 {
+		t.Fatalf("Error: expected deleted session not in the index got %v\n", ids)
+	}
+}
+
+func TestBoltStoreExpiryIndexBuilt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	store, err := boltsessionstore.Open(path, nil)
+	if err != nil {
+		t.Fatalf("Error: opening store:%s\n", err.Error())
+	}
+
+	now := time.Now()
+	expired := saveExpiring(t, store, now.Add(-time.Hour))
+	saveExpiring(t, store, now.Add(time.Hour))
+
+	// A file from before the store kept an expiry index.
+	err = store.DB.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket([]byte("sessions.expiry")); err != nil {
+			return err
+		}
+		return tx.DeleteBucket([]byte("sessions.sessionexpiry"))
+	})
+	if err != nil {
+		t.Fatalf("Error: removing expiry index:%s\n", err.Error())
+	}
+	store.Close()
+
+	store, err = boltsessionstore.Open(path, nil)
+	if err != nil {
+		t.Fatalf("Error: reopening store:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	if ids := deleteExpired(t, store, now); !sameIDs(ids, expired) {
+		t.Fatalf("Error: expected [%s] deleted got %v\n", expired, ids)
+	}
+
+	all, err := store.All()
+	if err != nil || len(all) != 1 {
+		t.Fatalf("Error: expected 1 session left got %d (%v)\n", len(all), err)
+	}
+}
@@ -0,0 +1,171 @@
+package boltsessionstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"github.com/boltdb/bolt"
+	"github.com/d2g/sessions"
+)
+
+// The records that can't be decoded, they're left in place.
+func (b *BoltStore) Corrupt() ([]sessions.QuarantinedRecord, error) {
+	corrupt := make([]sessions.QuarantinedRecord, 0)
+
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		var err error
+		_, corrupt, err = b.scan(tx)
+		return err
+	})
+
+	return corrupt, err
+}
+
+// Move the records that can't be decoded to the quarantine bucket, returns the number moved.
+// All does this itself, this is for running it without loading every session.
+func (b *BoltStore) QuarantineCorrupt() (int, error) {
+	n := 0
+
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		_, records, err := b.scan(tx)
+		if err != nil {
+			return err
+		}
+
+		n, err = b.quarantine(tx, records)
+		return err
+	})
+
+	return n, err
+}
+
+// Move the records to the quarantine bucket, returns the number moved.
+// Records saved again since they were read are left alone.
+func (b *BoltStore) quarantine(tx *bolt.Tx, records []sessions.QuarantinedRecord) (int, error) {
+	bkt := tx.Bucket(b.bucket(""))
+	if bkt == nil || len(records) == 0 {
+		return 0, nil
+	}
+
+	quarantine, err := tx.CreateBucketIfNotExists(b.bucket(quarantinesuffix))
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, record := range records {
+		if !bytes.Equal(bkt.Get([]byte(record.ID)), record.Data) {
+			continue
+		}
+
+		buf := new(bytes.Buffer)
+		enc := gob.NewEncoder(buf)
+		if err := enc.Encode(record); err != nil {
+			return n, err
+		}
+
+		if err := quarantine.Put([]byte(record.ID), buf.Bytes()); err != nil {
+			return n, err
+		}
+
+		if err := bkt.Delete([]byte(record.ID)); err != nil {
+			return n, err
+		}
+
+		if err := b.indexUser(tx, record.ID, ""); err != nil {
+			return n, err
+		}
+
+		if err := b.unindexExpiry(tx, record.ID); err != nil {
+			return n, err
+		}
+
+		b.logger().Warn("quarantined corrupt session", "session_id", record.ID, "error", record.Error)
+		n++
+	}
+
+	return n, nil
+}
+
+func (b *BoltStore) Quarantined() ([]sessions.QuarantinedRecord, error) {
+	records := make([]sessions.QuarantinedRecord, 0)
+
+	err := b.DB.View(func(tx *bolt.Tx) error {
+		quarantine := tx.Bucket(b.bucket(quarantinesuffix))
+		if quarantine == nil {
+			return nil
+		}
+
+		return quarantine.ForEach(func(k, v []byte) error {
+			record := sessions.QuarantinedRecord{}
+
+			dec := gob.NewDecoder(bytes.NewBuffer(v))
+			if err := dec.Decode(&record); err != nil {
+				return err
+			}
+
+			records = append(records, record)
+			return nil
+		})
+	})
+
+	return records, err
+}
+
+// Decode the quarantined record again, on success it's moved back to the sessions bucket.
+func (b *BoltStore) RetryQuarantined(id string) (sessions.Session, error) {
+	var s sessions.Session
+
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		quarantine := tx.Bucket(b.bucket(quarantinesuffix))
+		if quarantine == nil || quarantine.Get([]byte(id)) == nil {
+			return errors.New("boltsessionstore: session \"" + id + "\" not quarantined")
+		}
+
+		record := sessions.QuarantinedRecord{}
+		dec := gob.NewDecoder(bytes.NewBuffer(quarantine.Get([]byte(id))))
+		if err := dec.Decode(&record); err != nil {
+			return err
+		}
+
+		session, err := sessions.NewDefaultSession()
+		if err != nil {
+			return err
+		}
+
+		dec = gob.NewDecoder(bytes.NewBuffer(record.Data))
+		if err := dec.Decode(&session); err != nil {
+			return err
+		}
+
+		bkt, err := tx.CreateBucketIfNotExists(b.bucket(""))
+		if err != nil {
+			return err
+		}
+
+		if err := b.put(tx, bkt, session); err != nil {
+			return err
+		}
+
+		s = session
+		return quarantine.Delete([]byte(id))
+	})
+
+	return s, err
+}
+
+func (b *BoltStore) PurgeQuarantine() (int, error) {
+	n := 0
+
+	err := b.DB.Update(func(tx *bolt.Tx) error {
+		quarantine := tx.Bucket(b.bucket(quarantinesuffix))
+		if quarantine == nil {
+			return nil
+		}
+
+		n = quarantine.Stats().KeyN
+		return tx.DeleteBucket(b.bucket(quarantinesuffix))
+	})
+
+	return n, err
+}
@@ -0,0 +1,145 @@
+package boltsessionstore_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"github.com/boltdb/bolt"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/boltsessionstore"
+	"path/filepath"
+	"testing"
+)
+
+func TestBoltStoreQuarantine(t *testing.T) {
+	store, err := boltsessionstore.Open(filepath.Join(t.TempDir(), "sessions.db"), nil)
+	if err != nil {
+		t.Fatalf("Error: opening store:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	var _ sessions.Quarantine = store
+
+	good, _ := sessions.NewDefaultSession()
+	good.Set("Key", "Value")
+	store.Set(good)
+
+	broken, _ := sessions.NewDefaultSession()
+	broken.Set("Key", "Broken")
+	store.Set(broken)
+	id, _ := broken.ID()
+
+	// Keep the good encoding of broken and overwrite it with garbage.
+	var raw []byte
+	err = store.DB.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte("sessions"))
+		raw = append([]byte{}, bkt.Get([]byte(id))...)
+		return bkt.Put([]byte(id), []byte("corrupt"))
+	})
+	if err != nil {
+		t.Fatalf("Error: corrupting session:%s\n", err.Error())
+	}
+
+	// Corrupt only reports it.
+	corrupt, err := store.Corrupt()
+	if err != nil || len(corrupt) != 1 || corrupt[0].ID != id || string(corrupt[0].Data) != "corrupt" {
+		t.Fatalf("Error: unexpected corrupt records %+v (%v)\n", corrupt, err)
+	}
+
+	quarantined, err := store.Quarantined()
+	if err != nil || len(quarantined) != 0 {
+		t.Fatalf("Error: expected nothing quarantined by Corrupt got %d (%v)\n", len(quarantined), err)
+	}
+
+	// All skips the record and quarantines it.
+	all, err := store.All()
+	if err != nil || len(all) != 1 {
+		t.Fatalf("Error: expected 1 session got %d (%v)\n", len(all), err)
+	}
+
+	corrupt, err = store.Corrupt()
+	if err != nil || len(corrupt) != 0 {
+		t.Fatalf("Error: expected no corrupt records got %d (%v)\n", len(corrupt), err)
+	}
+
+	quarantined, err = store.Quarantined()
+	if err != nil || len(quarantined) != 1 || quarantined[0].ID != id || quarantined[0].Error == "" {
+		t.Fatalf("Error: unexpected quarantined records %+v (%v)\n", quarantined, err)
+	}
+
+	// Still undecodable, it stays in the quarantine.
+	if _, err := store.RetryQuarantined(id); err == nil {
+		t.Fatalf("Error: expected retrying a corrupt record to fail\n")
+	}
+
+	if _, err := store.RetryQuarantined("missing"); err == nil {
+		t.Fatalf("Error: expected retrying an unknown record to fail\n")
+	}
+
+	// Repair the quarantined record, as registering a missing gob type would.
+	record := quarantined[0]
+	record.Data = raw
+	err = store.DB.Update(func(tx *bolt.Tx) error {
+		buf := new(bytes.Buffer)
+		if err := gob.NewEncoder(buf).Encode(record); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("sessions.quarantine")).Put([]byte(id), buf.Bytes())
+	})
+	if err != nil {
+		t.Fatalf("Error: repairing record:%s\n", err.Error())
+	}
+
+	s, err := store.RetryQuarantined(id)
+	if err != nil {
+		t.Fatalf("Error: retrying quarantined record:%s\n", err.Error())
+	}
+
+	if v, _ := s.Get("Key"); v != "Broken" {
+		t.Fatalf("Error: expected \"Broken\" got \"%v\"\n", v)
+	}
+
+	s, err = store.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting restored session:%s\n", err.Error())
+	}
+
+	if v, _ := s.Get("Key"); v != "Broken" {
+		t.Fatalf("Error: session not restored, got \"%v\"\n", v)
+	}
+
+	quarantined, err = store.Quarantined()
+	if err != nil || len(quarantined) != 0 {
+		t.Fatalf("Error: expected empty quarantine got %d (%v)\n", len(quarantined), err)
+	}
+
+	// Purge
+	err = store.DB.Update(func(tx *bolt.Tx) error {
+		bkt := tx.Bucket([]byte("sessions"))
+		if err := bkt.Put([]byte("a"), []byte("corrupt")); err != nil {
+			return err
+		}
+		return bkt.Put([]byte("b"), []byte("corrupt"))
+	})
+	if err != nil {
+		t.Fatalf("Error: corrupting sessions:%s\n", err.Error())
+	}
+
+	if n, err := store.QuarantineCorrupt(); err != nil || n != 2 {
+		t.Fatalf("Error: expected 2 records quarantined got %d (%v)\n", n, err)
+	}
+
+	n, err := store.PurgeQuarantine()
+	if err != nil || n != 2 {
+		t.Fatalf("Error: expected 2 records purged got %d (%v)\n", n, err)
+	}
+
+	quarantined, err = store.Quarantined()
+	if err != nil || len(quarantined) != 0 {
+		t.Fatalf("Error: expected empty quarantine got %d (%v)\n", len(quarantined), err)
+	}
+
+	all, err = store.All()
+	if err != nil || len(all) != 2 {
+		t.Fatalf("Error: expected 2 sessions got %d (%v)\n", len(all), err)
+	}
+}
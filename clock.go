@@ -0,0 +1,24 @@
+package sessions
+
+import (
+	"time"
+)
+
+// Clock tells the time used for session expiry, so expiry can be tested without sleeping.
+type Clock interface {
+	Now() time.Time
+}
+
+// SystemClock is the real time, it's what's used when no Clock is set.
+type SystemClock struct{}
+
+func (t SystemClock) Now() time.Time {
+	return time.Now()
+}
+
+func now(c Clock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+	return c.Now()
+}
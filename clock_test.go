@@ -0,0 +1,92 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSessionInfoClock(t *testing.T) {
+	clock := sessionstest.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Timeout = time.Hour
+	si.Store = NewMapStore()
+	si.Clock = clock
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := si.GetSession(r)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+		s.Set("Key", "Value")
+	}))
+
+	r := sessionstest.NewRequest(t, si, "GET", "/", nil, map[interface{}]interface{}{"Key": "Value"})
+	clock.Advance(30 * time.Minute)
+
+	w := sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, r)
+
+	// Saving the session extends it by the timeout from the clock's now.
+	s := w.Session(t)
+	if s == nil {
+		t.Fatalf("Error: session not saved\n")
+	}
+
+	if !s.Expiry().Equal(clock.Now().Add(time.Hour)) {
+		t.Fatalf("Error: expected expiry %v got %v\n", clock.Now().Add(time.Hour), s.Expiry())
+	}
+
+	if w.Cookie().MaxAge != 3600 {
+		t.Fatalf("Error: expected max age 3600 got %d\n", w.Cookie().MaxAge)
+	}
+
+	// Once the clock passes the expiry the session is collected.
+	n, err := si.CollectExpired()
+	if err != nil {
+		t.Fatalf("Error: collecting expired sessions:%s\n", err.Error())
+	}
+
+	if n != 0 {
+		t.Fatalf("Error: expected no sessions collected got %d\n", n)
+	}
+
+	clock.Advance(2 * time.Hour)
+
+	n, err = si.CollectExpired()
+	if err != nil {
+		t.Fatalf("Error: collecting expired sessions:%s\n", err.Error())
+	}
+
+	if n != 1 {
+		t.Fatalf("Error: expected 1 session collected got %d\n", n)
+	}
+}
+
+func TestMigrateClock(t *testing.T) {
+	clock := sessionstest.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	src := NewMapStore()
+	s, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	s.Set("Key", "Value")
+	s.SetExpiry(clock.Now().Add(time.Minute))
+	src.Set(s)
+
+	clock.Advance(time.Hour)
+
+	result, err := sessions.Migrate(NewMapStore(), src, sessions.MigrateOptions{Clock: clock})
+	if err != nil {
+		t.Fatalf("Error: migrating:%s\n", err.Error())
+	}
+
+	if result.Expired != 1 || result.Migrated != 0 {
+		t.Fatalf("Error: expected the session to be expired got %+v\n", result)
+	}
+}
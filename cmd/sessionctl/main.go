@@ -0,0 +1,281 @@
+// Command sessionctl inspects and maintains a session store.
+//
+//	sessionctl -store bolt:sessions.db list
+//	sessionctl -store bolt:sessions.db dump <session id>
+//	sessionctl -store bolt:sessions.db delete <session id>
+//	sessionctl -store bolt:sessions.db purge-expired
+//	sessionctl -store bolt:sessions.db stats
+//	sessionctl -store bolt:sessions.db export <file>
+//	sessionctl -store bolt:sessions.db import <file>
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/internal/storespec"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+const usage = `Usage: sessionctl -store <store> <command> [arguments]
+
+Commands:
+	list             list every session with its expiry and number of keys
+	dump <id>        print the values held in a session
+	delete <id>      delete a session
+	purge-expired    delete every expired session
+	stats            print statistics about the store
+	export <file>    write every session to an archive
+	import <file>    save every session in an archive to the store
+`
+
+func main() {
+	spec := flag.String("store", "", "session store, "+storespec.Usage)
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *spec == "" || flag.NArg() < 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	store, closer, err := storespec.Open(*spec, mode(flag.Arg(0)))
+	if err != nil {
+		log.Fatalf("Error: opening store: %s\n", err.Error())
+	}
+
+	err = run(os.Stdout, store, flag.Arg(0), flag.Args()[1:])
+	closer.Close()
+
+	if err != nil {
+		log.Fatalf("Error: %s\n", err.Error())
+	}
+}
+
+// How the store is opened for command, only import creates it.
+func mode(command string) storespec.Mode {
+	switch command {
+	case "delete", "purge-expired":
+		return storespec.ReadWrite
+	case "import":
+		return storespec.Create
+	}
+	return storespec.ReadOnly
+}
+
+func run(w io.Writer, store sessions.SessionStore, command string, args []string) error {
+	switch command {
+	case "list":
+		return list(w, store)
+	case "dump":
+		if len(args) != 1 {
+			return errors.New("dump expects a session id")
+		}
+		return dump(w, store, args[0])
+	case "delete":
+		if len(args) != 1 {
+			return errors.New("delete expects a session id")
+		}
+		return store.Delete(args[0])
+	case "purge-expired":
+		return purge(w, store)
+	case "stats":
+		return stats(w, store)
+	case "export":
+		if len(args) != 1 {
+			return errors.New("export expects a file")
+		}
+		return export(w, store, args[0])
+	case "import":
+		if len(args) != 1 {
+			return errors.New("import expects a file")
+		}
+		return restore(w, store, args[0])
+	}
+
+	return errors.New("unknown command \"" + command + "\"")
+}
+
+func list(w io.Writer, store sessions.SessionStore) error {
+	all, err := store.All()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].Expiry().Before(all[j].Expiry())
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tEXPIRES\tKEYS")
+
+	for _, s := range all {
+		id, err := s.ID()
+		if err != nil {
+			return err
+		}
+
+		keys, err := s.Keys()
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%d\n", id, s.Expiry().Format(time.RFC3339), len(keys))
+	}
+
+	return tw.Flush()
+}
+
+func dump(w io.Writer, store sessions.SessionStore, id string) error {
+	s, err := store.Get(id)
+	if err != nil {
+		return err
+	}
+
+	// Stores hand back a new session when the ID isn't found.
+	if got, err := s.ID(); err != nil || got != id {
+		return errors.New("session \"" + id + "\" not found")
+	}
+
+	keys, err := s.Keys()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "ID:      %s\n", id)
+	fmt.Fprintf(w, "Expires: %s\n", s.Expiry().Format(time.RFC3339))
+
+	for _, key := range keys {
+		value, err := s.Get(key)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%#v: %#v\n", key, value)
+	}
+
+	return nil
+}
+
+func purge(w io.Writer, store sessions.SessionStore) error {
+	if deleter, ok := store.(sessions.ExpiredDeleter); ok {
+		ids, err := deleter.DeleteExpired(time.Now())
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(w, "purged %d expired sessions\n", len(ids))
+		return nil
+	}
+
+	all, err := store.All()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	purged := 0
+
+	for _, s := range all {
+		if !s.Expiry().Before(now) {
+			continue
+		}
+
+		id, err := s.ID()
+		if err != nil {
+			return err
+		}
+
+		if err := store.Delete(id); err != nil {
+			return err
+		}
+		purged++
+	}
+
+	fmt.Fprintf(w, "purged %d expired sessions\n", purged)
+	return nil
+}
+
+func stats(w io.Writer, store sessions.SessionStore) error {
+	all, err := store.All()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expired, keys := 0, 0
+	var first, last time.Time
+
+	for i, s := range all {
+		k, err := s.Keys()
+		if err != nil {
+			return err
+		}
+		keys += len(k)
+
+		if s.Expiry().Before(now) {
+			expired++
+		}
+
+		if i == 0 || s.Expiry().Before(first) {
+			first = s.Expiry()
+		}
+
+		if i == 0 || s.Expiry().After(last) {
+			last = s.Expiry()
+		}
+	}
+
+	fmt.Fprintf(w, "Sessions:       %d\n", len(all))
+	fmt.Fprintf(w, "Active:         %d\n", len(all)-expired)
+	fmt.Fprintf(w, "Expired:        %d\n", expired)
+	fmt.Fprintf(w, "Keys:           %d\n", keys)
+
+	if len(all) > 0 {
+		fmt.Fprintf(w, "Keys/Session:   %.1f\n", float64(keys)/float64(len(all)))
+		fmt.Fprintf(w, "First Expiry:   %s\n", first.Format(time.RFC3339))
+		fmt.Fprintf(w, "Last Expiry:    %s\n", last.Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+func export(w io.Writer, store sessions.SessionStore, filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	n, err := sessions.Export(store, f)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "exported %d sessions\n", n)
+	return nil
+}
+
+func restore(w io.Writer, store sessions.SessionStore, filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := sessions.Import(store, f)
+	fmt.Fprintf(w, "imported %d sessions\n", n)
+	return err
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/internal/storespec"
+	"github.com/d2g/sessions/sessionstest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// A store with an active session and an expired one.
+func newStore(t *testing.T) (*sessionstest.MemoryStore, string, string) {
+	store := sessionstest.NewMemoryStore()
+
+	active, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	active.Set("User", "alice")
+	active.Set("Cart", 3)
+	active.SetExpiry(time.Now().Add(time.Hour))
+	store.Set(active)
+
+	expired, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	expired.Set("User", "bob")
+	expired.SetExpiry(time.Now().Add(-time.Hour))
+	store.Set(expired)
+
+	activeid, _ := active.ID()
+	expiredid, _ := expired.ID()
+	return store, activeid, expiredid
+}
+
+func runCommand(t *testing.T, store sessions.SessionStore, command string, args ...string) string {
+	w := new(bytes.Buffer)
+	if err := run(w, store, command, args); err != nil {
+		t.Fatalf("Error: running %s:%s\n", command, err.Error())
+	}
+	return w.String()
+}
+
+func exists(t *testing.T, store sessions.SessionStore, id string) bool {
+	s, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	got, _ := s.ID()
+	return got == id
+}
+
+func TestList(t *testing.T) {
+	store, activeid, expiredid := newStore(t)
+
+	out := runCommand(t, store, "list")
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+
+	// Header then sessions in expiry order.
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") {
+		t.Fatalf("Error: unexpected list output %q\n", out)
+	}
+
+	if !strings.HasPrefix(lines[1], expiredid) || !strings.HasPrefix(lines[2], activeid) {
+		t.Fatalf("Error: sessions not listed in expiry order %q\n", out)
+	}
+
+	if !strings.HasSuffix(lines[2], " 2") {
+		t.Fatalf("Error: expected 2 keys in %q\n", lines[2])
+	}
+}
+
+func TestDump(t *testing.T) {
+	store, activeid, _ := newStore(t)
+
+	out := runCommand(t, store, "dump", activeid)
+	for _, want := range []string{"ID:      " + activeid, `"User": "alice"`, `"Cart": 3`} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("Error: expected %q in %q\n", want, out)
+		}
+	}
+
+	if err := run(new(bytes.Buffer), store, "dump", []string{"missing"}); err == nil {
+		t.Fatalf("Error: expected error dumping missing session\n")
+	}
+}
+
+func TestDelete(t *testing.T) {
+	store, activeid, expiredid := newStore(t)
+
+	runCommand(t, store, "delete", activeid)
+
+	if exists(t, store, activeid) || !exists(t, store, expiredid) {
+		t.Fatalf("Error: wrong session deleted\n")
+	}
+}
+
+func TestPurgeExpired(t *testing.T) {
+	store, activeid, expiredid := newStore(t)
+
+	out := runCommand(t, store, "purge-expired")
+	if out != "purged 1 expired sessions\n" {
+		t.Fatalf("Error: unexpected purge output %q\n", out)
+	}
+
+	if !exists(t, store, activeid) || exists(t, store, expiredid) {
+		t.Fatalf("Error: wrong session purged\n")
+	}
+}
+
+func TestStats(t *testing.T) {
+	store, _, _ := newStore(t)
+
+	out := runCommand(t, store, "stats")
+	for _, want := range []string{"Sessions:       2", "Active:         1", "Expired:        1", "Keys:           3", "Keys/Session:   1.5"} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("Error: expected %q in %q\n", want, out)
+		}
+	}
+
+	out = runCommand(t, sessionstest.NewMemoryStore(), "stats")
+	if strings.Contains(out, "Keys/Session") {
+		t.Fatalf("Error: unexpected averages for an empty store %q\n", out)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	store, activeid, expiredid := newStore(t)
+	filename := filepath.Join(t.TempDir(), "sessions.archive")
+
+	if out := runCommand(t, store, "export", filename); out != "exported 2 sessions\n" {
+		t.Fatalf("Error: unexpected export output %q\n", out)
+	}
+
+	restored := sessionstest.NewMemoryStore()
+	if out := runCommand(t, restored, "import", filename); out != "imported 2 sessions\n" {
+		t.Fatalf("Error: unexpected import output %q\n", out)
+	}
+
+	if !exists(t, restored, activeid) || !exists(t, restored, expiredid) {
+		t.Fatalf("Error: sessions not imported\n")
+	}
+}
+
+func TestArguments(t *testing.T) {
+	store, _, _ := newStore(t)
+
+	checks := []struct {
+		command string
+		args    []string
+	}{
+		{"dump", nil},
+		{"dump", []string{"a", "b"}},
+		{"delete", nil},
+		{"export", nil},
+		{"import", nil},
+		{"import", []string{filepath.Join(t.TempDir(), "missing")}},
+		{"unknown", nil},
+	}
+
+	for _, c := range checks {
+		if err := run(new(bytes.Buffer), store, c.command, c.args); err == nil {
+			t.Fatalf("Error: expected error for %s %v\n", c.command, c.args)
+		}
+	}
+}
+
+func TestMode(t *testing.T) {
+	expected := map[string]storespec.Mode{
+		"list":          storespec.ReadOnly,
+		"dump":          storespec.ReadOnly,
+		"stats":         storespec.ReadOnly,
+		"export":        storespec.ReadOnly,
+		"delete":        storespec.ReadWrite,
+		"purge-expired": storespec.ReadWrite,
+		"import":        storespec.Create,
+	}
+
+	for command, m := range expected {
+		if mode(command) != m {
+			t.Fatalf("Error: expected mode %d for %s got %d\n", m, command, mode(command))
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Command sessionmigrate copies the sessions from one store to another.
+//
+//	sessionmigrate -from unqlite:sessions.unqlite -to bolt:sessions.db
+//
+// The source must exist and is opened read only, the destination is created if it doesn't exist.
+// Expired sessions are skipped and every copied session is read back from the destination.
+// Run it while the application writes through a sessions.DualWriteStore to cut over without logging anyone out.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/internal/storespec"
+	"log"
+	"os"
+)
+
+func main() {
+	from := flag.String("from", "", "source store, "+storespec.Usage)
+	to := flag.String("to", "", "destination store, "+storespec.Usage)
+	expired := flag.Bool("include-expired", false, "copy expired sessions")
+	verify := flag.Bool("verify", true, "read back every copied session from the destination")
+	flag.Parse()
+
+	if *from == "" || *to == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	src, srccloser, err := storespec.Open(*from, storespec.ReadOnly)
+	if err != nil {
+		log.Fatalf("Error: opening source store: %s\n", err.Error())
+	}
+	defer srccloser.Close()
+
+	dst, dstcloser, err := storespec.Open(*to, storespec.Create)
+	if err != nil {
+		log.Fatalf("Error: opening destination store: %s\n", err.Error())
+	}
+	defer dstcloser.Close()
+
+	result, err := sessions.Migrate(dst, src, sessions.MigrateOptions{
+		IncludeExpired: *expired,
+		Verify:         *verify,
+	})
+
+	fmt.Printf("read: %d migrated: %d expired: %d deleted: %d existing: %d verified: %d\n", result.Read, result.Migrated, result.Expired, result.Deleted, result.Existing, result.Verified)
+
+	if err != nil {
+		log.Fatalf("Error: migrating sessions: %s\n", err.Error())
+	}
+
+	if *verify && result.Verified != result.Migrated {
+		log.Fatalf("Error: only %d of %d sessions verified\n", result.Verified, result.Migrated)
+	}
+}
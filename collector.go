@@ -0,0 +1,75 @@
+package sessions
+
+import (
+	"time"
+)
+
+// Delete the expired sessions from the store, returns the number of sessions deleted.
+// Stores that are an ExpiredDeleter remove the sessions themselves so the OnExpire hook only gets the ID.
+func (t *SessionInfo) CollectExpired() (int, error) {
+	if store, ok := t.Store.(ExpiredDeleter); ok {
+		ids, err := store.DeleteExpired(now(t.Clock))
+		for _, id := range ids {
+			t.Hooks.fire(t.Hooks.OnExpire, id, nil, nil)
+		}
+		return len(ids), err
+	}
+
+	all, err := t.Store.All()
+	if err != nil {
+		return 0, err
+	}
+
+	now := now(t.Clock)
+	n := 0
+
+	for _, s := range all {
+		if !s.Expiry().Before(now) {
+			continue
+		}
+
+		id, err := s.ID()
+		if err != nil {
+			return n, err
+		}
+
+		if err := t.Store.Delete(id); err != nil {
+			return n, err
+		}
+		n++
+
+		t.Hooks.fire(t.Hooks.OnExpire, id, s, nil)
+	}
+
+	return n, nil
+}
+
+// Run CollectExpired every interval until the returned function is called.
+func (t *SessionInfo) StartCollector(interval time.Duration) (stop func()) {
+	quit := make(chan struct{})
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-quit:
+				return
+			case <-ticker.C:
+			}
+
+			if _, err := t.CollectExpired(); err != nil {
+				t.logger().Error("collecting expired sessions", "error", err)
+			}
+		}
+	}()
+
+	return func() {
+		close(quit)
+		<-done
+	}
+}
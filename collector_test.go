@@ -0,0 +1,96 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"testing"
+	"time"
+)
+
+func TestCollectExpired(t *testing.T) {
+	store := NewMapStore()
+
+	si := sessions.SessionInfo{}
+	si.Store = store
+
+	for i := 0; i < 4; i++ {
+		s, err := sessions.NewDefaultSession()
+		if err != nil {
+			t.Fatalf("Error: creating new session:%s\n", err.Error())
+		}
+
+		if i%2 == 0 {
+			s.SetExpiry(time.Now().Add(time.Hour))
+		} else {
+			s.SetExpiry(time.Now().Add(-time.Hour))
+		}
+		store.Set(s)
+	}
+
+	expired := 0
+	si.Hooks.OnExpire = func(e sessions.SessionEvent) {
+		if e.Request != nil || e.Session == nil {
+			t.Fatalf("Error: unexpected expire event %+v\n", e)
+		}
+		expired++
+	}
+
+	n, err := si.CollectExpired()
+	if err != nil {
+		t.Fatalf("Error: collecting expired sessions:%s\n", err.Error())
+	}
+
+	if n != 2 || expired != 2 || len(store.Records) != 2 {
+		t.Fatalf("Error: expected 2 sessions collected got %d\n", n)
+	}
+
+	stop := si.StartCollector(time.Millisecond)
+	stop()
+}
+
+// A MapStore that removes expired sessions itself.
+type ExpiringMapStore struct {
+	*MapStore
+}
+
+func (t *ExpiringMapStore) DeleteExpired(before time.Time) ([]string, error) {
+	all, err := t.All()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0)
+	for _, s := range all {
+		if s.Expiry().Before(before) {
+			id, _ := s.ID()
+			t.Delete(id)
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func TestCollectExpiredDeleter(t *testing.T) {
+	store := &ExpiringMapStore{NewMapStore()}
+
+	si := sessions.SessionInfo{}
+	si.Store = store
+
+	s, _ := sessions.NewDefaultSession()
+	s.SetExpiry(time.Now().Add(-time.Hour))
+	store.Set(s)
+	id, _ := s.ID()
+
+	expired := make([]string, 0)
+	si.Hooks.OnExpire = func(e sessions.SessionEvent) {
+		expired = append(expired, e.ID)
+	}
+
+	n, err := si.CollectExpired()
+	if err != nil {
+		t.Fatalf("Error: collecting expired sessions:%s\n", err.Error())
+	}
+
+	if n != 1 || len(expired) != 1 || expired[0] != id {
+		t.Fatalf("Error: expected session %s collected got %v\n", id, expired)
+	}
+}
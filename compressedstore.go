@@ -0,0 +1,206 @@
+package sessions
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"io"
+)
+
+const (
+	compressedkey string = "sessions.compressed"
+)
+
+var (
+	ErrUnknownCompression = errors.New("sessions: session compressed with unknown compressor")
+)
+
+// Compressor is a compression algorithm usable by the CompressedStore.
+type Compressor interface {
+	// The header byte marking records written by this compressor.
+	// 0 is reserved.
+	Header() byte
+
+	// Wrap w so that everything written is compressed.
+	NewWriter(w io.Writer) (io.WriteCloser, error)
+
+	// Wrap r so that everything read is decompressed.
+	NewReader(r io.Reader) (io.ReadCloser, error)
+}
+
+// Gzip compresses with compress/gzip at the given level (0 uses the default level).
+type Gzip struct {
+	Level int
+}
+
+func (t Gzip) Header() byte {
+	return 1
+}
+
+func (t Gzip) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if t.Level == 0 {
+		return gzip.NewWriter(w), nil
+	}
+	return gzip.NewWriterLevel(w, t.Level)
+}
+
+func (t Gzip) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return gzip.NewReader(r)
+}
+
+// Flate compresses with compress/flate at the given level (0 uses the default level).
+type Flate struct {
+	Level int
+}
+
+func (t Flate) Header() byte {
+	return 2
+}
+
+func (t Flate) NewWriter(w io.Writer) (io.WriteCloser, error) {
+	if t.Level == 0 {
+		return flate.NewWriter(w, flate.DefaultCompression)
+	}
+	return flate.NewWriter(w, t.Level)
+}
+
+func (t Flate) NewReader(r io.Reader) (io.ReadCloser, error) {
+	return flate.NewReader(r), nil
+}
+
+// CompressedStore compresses sessions whose encoding is larger than a threshold before they reach the inner store.
+//
+// Smaller sessions are stored untouched, so records written before the store was wrapped are read as is.
+type CompressedStore struct {
+	store       SessionStore
+	threshold   int
+	compressor  Compressor
+	compressors map[byte]Compressor
+}
+
+// Create a CompressedStore in front of store.
+// Sessions that encode to more than threshold bytes are compressed with compressor,
+// others lists any further compressors that existing records may have been written with.
+func NewCompressedStore(store SessionStore, threshold int, compressor Compressor, others ...Compressor) (*CompressedStore, error) {
+	t := &CompressedStore{
+		store:       store,
+		threshold:   threshold,
+		compressor:  compressor,
+		compressors: make(map[byte]Compressor),
+	}
+
+	for _, c := range append(others, compressor) {
+		if c.Header() == 0 {
+			return nil, errors.New("sessions: compressor header 0 is reserved")
+		}
+		t.compressors[c.Header()] = c
+	}
+
+	return t, nil
+}
+
+func (t *CompressedStore) Get(id string) (Session, error) {
+	s, err := t.store.Get(id)
+	if err != nil {
+		return s, err
+	}
+
+	return t.decompress(s)
+}
+
+func (t *CompressedStore) Set(s Session) error {
+	s, err := t.compress(s)
+	if err != nil {
+		return err
+	}
+
+	return t.store.Set(s)
+}
+
+func (t *CompressedStore) Delete(id string) error {
+	return t.store.Delete(id)
+}
+
+// The user binding is kept outside the envelope so the inner store's index can be used.
+func (t *CompressedStore) UserSessions(user string) ([]string, error) {
+	return userSessions(t.store, user)
+}
+
+func (t *CompressedStore) All() ([]Session, error) {
+	all, err := t.store.All()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range all {
+		all[i], err = t.decompress(all[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return all, nil
+}
+
+// Record layout: compressor header byte, compressed encoding.
+func (t *CompressedStore) compress(s Session) (Session, error) {
+	encoded, err := s.GobEncode()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(encoded) <= t.threshold {
+		return s, nil
+	}
+
+	buf := bytes.NewBuffer([]byte{t.compressor.Header()})
+	w, err := t.compressor.NewWriter(buf)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(encoded); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	user, err := SessionUser(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return newEnvelope(s, compressedkey, buf.Bytes(), user)
+}
+
+func (t *CompressedStore) decompress(s Session) (Session, error) {
+	record, ok, err := openEnvelope(s, compressedkey)
+	if err != nil || !ok {
+		return s, err
+	}
+
+	if len(record) < 1 {
+		return nil, ErrUnknownCompression
+	}
+
+	c, ok := t.compressors[record[0]]
+	if !ok {
+		return nil, ErrUnknownCompression
+	}
+
+	r, err := c.NewReader(bytes.NewReader(record[1:]))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	encoded, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeSession(encoded)
+}
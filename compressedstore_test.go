@@ -0,0 +1,101 @@
+package sessions_test
+
+import (
+	"bytes"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"strings"
+	"testing"
+)
+
+func TestCompressedStore(t *testing.T) {
+	inner := NewMapStore()
+
+	cs, err := sessions.NewCompressedStore(inner, 512, sessions.Gzip{}, sessions.Flate{})
+	if err != nil {
+		t.Fatalf("Error: creating compressed store:%s\n", err.Error())
+	}
+
+	small, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	small.Set("Key", "Value")
+
+	large, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	large.Set("Key", strings.Repeat("Value", 1000))
+
+	for _, s := range []sessions.Session{small, large} {
+		err = cs.Set(s)
+		if err != nil {
+			t.Fatalf("Error: saving session:%s\n", err.Error())
+		}
+	}
+
+	smallid, _ := small.ID()
+	largeid, _ := large.ID()
+
+	// Small sessions are stored as is.
+	if !bytes.Contains(inner.Records[smallid], []byte("Value")) {
+		t.Fatalf("Error: small session shouldn't be compressed\n")
+	}
+
+	if len(inner.Records[largeid]) >= 5000 {
+		t.Fatalf("Error: large session not compressed, %d bytes\n", len(inner.Records[largeid]))
+	}
+
+	for id, expected := range map[string]string{smallid: "Value", largeid: strings.Repeat("Value", 1000)} {
+		s, err := cs.Get(id)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		v, err := s.Get("Key")
+		if err != nil {
+			t.Fatalf("Error: getting value from session:%s\n", err.Error())
+		}
+
+		if v != expected {
+			t.Fatalf("Error: unexpected value for session %s\n", id)
+		}
+	}
+
+	// Records written with an older compressor can still be read.
+	cs, err = sessions.NewCompressedStore(inner, 512, sessions.Flate{}, sessions.Gzip{})
+	if err != nil {
+		t.Fatalf("Error: creating compressed store:%s\n", err.Error())
+	}
+
+	all, err := cs.All()
+	if err != nil {
+		t.Fatalf("Error: getting all sessions:%s\n", err.Error())
+	}
+
+	if len(all) != 2 {
+		t.Fatalf("Error: expected 2 sessions got %d\n", len(all))
+	}
+
+	// Without gzip the large record is unreadable.
+	cs, err = sessions.NewCompressedStore(inner, 512, sessions.Flate{})
+	if err != nil {
+		t.Fatalf("Error: creating compressed store:%s\n", err.Error())
+	}
+
+	_, err = cs.Get(largeid)
+	if err != sessions.ErrUnknownCompression {
+		t.Fatalf("Error: expected unknown compression error got %v\n", err)
+	}
+}
+
+func TestCompressedStoreConformance(t *testing.T) {
+	sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+		cs, err := sessions.NewCompressedStore(NewMapStore(), 64, sessions.Gzip{})
+		if err != nil {
+			t.Fatalf("Error: creating compressed store:%s\n", err.Error())
+		}
+		return cs
+	})
+}
@@ -0,0 +1,186 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"html/template"
+	"io"
+	"net/http"
+)
+
+const (
+	csrfkey string = "sessions.csrf"
+
+	csrfsecretlength int = 32
+)
+
+var (
+	ErrCSRFToken = errors.New("sessions: csrf token missing or invalid")
+)
+
+// CSRF checks requests that change state carry a token only pages served from the session could have.
+//
+// Each session gets a random secret, the tokens handed out are the secret masked with a fresh one time pad
+// so the token is different on every page (stopping BREACH style compression attacks) but any of them is accepted.
+// The handler must be inside SessionInfo.GetHandler so the secret is saved with the session.
+type CSRF struct {
+	Sessions *SessionInfo
+
+	// The form field the token is read from, defaults to "csrf_token".
+	FieldName string
+
+	// The header the token is read from (checked before the form), defaults to "X-CSRF-Token".
+	HeaderName string
+
+	// Called when the token is missing or wrong, defaults to a 403.
+	ErrorHandler http.Handler
+}
+
+func NewCSRF(sessions *SessionInfo) *CSRF {
+	return &CSRF{
+		Sessions: sessions,
+	}
+}
+
+// Wrapper function to allow http.handler chaining.
+// GET, HEAD, OPTIONS and TRACE requests are passed through, everything else needs a valid token.
+// Passed through requests are given a secret up front so a client without a session (i.e. on the login form)
+// is sent one with the response, even when the page has been partly written before it asks for a token.
+func (t *CSRF) GetHandler(c http.Handler) http.Handler {
+	return &csrfHandler{
+		c,
+		t,
+	}
+}
+
+// A masked token for the request's session, the session's secret is created if it doesn't have one.
+func (t *CSRF) Token(r *http.Request) (string, error) {
+	secret, err := t.secret(r, true)
+	if err != nil {
+		return "", err
+	}
+
+	pad := make([]byte, csrfsecretlength)
+	if _, err := io.ReadFull(rand.Reader, pad); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(append(pad, xor(pad, secret)...)), nil
+}
+
+// A hidden form input holding a token, for templates.
+func (t *CSRF) Field(r *http.Request) (template.HTML, error) {
+	token, err := t.Token(r)
+	if err != nil {
+		return "", err
+	}
+
+	return template.HTML(`<input type="hidden" name="` + template.HTMLEscapeString(t.fieldName()) + `" value="` + token + `">`), nil
+}
+
+// Check the request carries a token for its session.
+func (t *CSRF) Verify(r *http.Request) error {
+	secret, err := t.secret(r, false)
+	if err != nil {
+		return err
+	}
+
+	token := r.Header.Get(t.headerName())
+	if token == "" {
+		token = r.PostFormValue(t.fieldName())
+	}
+
+	masked, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || secret == nil || len(masked) != 2*csrfsecretlength {
+		return ErrCSRFToken
+	}
+
+	if subtle.ConstantTimeCompare(xor(masked[:csrfsecretlength], masked[csrfsecretlength:]), secret) != 1 {
+		return ErrCSRFToken
+	}
+	return nil
+}
+
+// The session's secret, nil if it hasn't got one and create is false.
+func (t *CSRF) secret(r *http.Request, create bool) ([]byte, error) {
+	s, err := t.Sessions.GetSession(r)
+	if err != nil {
+		return nil, err
+	}
+
+	v, err := s.Get(csrfkey)
+	if err != nil {
+		return nil, err
+	}
+
+	if secret, ok := v.([]byte); ok && len(secret) == csrfsecretlength {
+		return secret, nil
+	}
+
+	if !create {
+		return nil, nil
+	}
+
+	secret := make([]byte, csrfsecretlength)
+	if _, err := io.ReadFull(rand.Reader, secret); err != nil {
+		return nil, err
+	}
+
+	//The cached session, it's saved with the rest of the request's changes.
+	if err := s.Set(csrfkey, secret); err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func (t *CSRF) fieldName() string {
+	if t.FieldName == "" {
+		return "csrf_token"
+	}
+	return t.FieldName
+}
+
+func (t *CSRF) headerName() string {
+	if t.HeaderName == "" {
+		return "X-CSRF-Token"
+	}
+	return t.HeaderName
+}
+
+func xor(a, b []byte) []byte {
+	out := make([]byte, len(a))
+	for i := range a {
+		out[i] = a[i] ^ b[i]
+	}
+	return out
+}
+
+type csrfHandler struct {
+	http.Handler
+	*CSRF
+}
+
+func (t csrfHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS", "TRACE":
+		if _, err := t.secret(r, true); err != nil {
+			http.Error(w, err.Error(), 500)
+			return
+		}
+	default:
+		if err := t.Verify(r); err != nil {
+			t.Sessions.logger().Warn("rejecting request", "method", r.Method, "path", r.URL.Path, "error", err)
+
+			if t.ErrorHandler != nil {
+				t.ErrorHandler.ServeHTTP(w, r)
+			} else {
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			}
+			return
+		}
+	}
+
+	t.Handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,146 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCSRF(t *testing.T) {
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Timeout = time.Hour
+	si.Store = NewMapStore()
+
+	csrf := sessions.NewCSRF(si)
+
+	tokens := make([]string, 0)
+	h := si.GetHandler(csrf.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "GET" {
+			token, err := csrf.Token(r)
+			if err != nil {
+				t.Fatalf("Error: getting csrf token:%s\n", err.Error())
+			}
+			tokens = append(tokens, token)
+		}
+	})))
+
+	values := map[interface{}]interface{}{"User": "alice"}
+	r := sessionstest.NewRequest(t, si, "GET", "/", nil, values)
+	cookie, _ := r.Cookie(si.Cookie.Name)
+
+	// Tokens are masked differently every time.
+	for i := 0; i < 2; i++ {
+		r = httptest.NewRequest("GET", "/", nil)
+		r.AddCookie(cookie)
+
+		w := sessionstest.NewRecorder(si)
+		h.ServeHTTP(w, r)
+
+		if w.Code != 200 {
+			t.Fatalf("Error: expected 200 got %d\n", w.Code)
+		}
+	}
+
+	if len(tokens) != 2 || tokens[0] == tokens[1] {
+		t.Fatalf("Error: expected two different tokens got %v\n", tokens)
+	}
+
+	// Another session's token isn't accepted.
+	other := sessionstest.NewRequest(t, si, "GET", "/", nil, values)
+	w := sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, other)
+	foreign := tokens[2]
+
+	post := func(form url.Values, header string) int {
+		r := httptest.NewRequest("POST", "/", strings.NewReader(form.Encode()))
+		r.AddCookie(cookie)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if header != "" {
+			r.Header.Set("X-CSRF-Token", header)
+		}
+
+		w := sessionstest.NewRecorder(si)
+		h.ServeHTTP(w, r)
+		return w.Code
+	}
+
+	checks := []struct {
+		name   string
+		form   url.Values
+		header string
+		code   int
+	}{
+		{"missing", url.Values{}, "", 403},
+		{"garbage", url.Values{"csrf_token": {"garbage"}}, "", 403},
+		{"foreign", url.Values{"csrf_token": {foreign}}, "", 403},
+		{"form", url.Values{"csrf_token": {tokens[0]}}, "", 200},
+		{"header", url.Values{}, tokens[1], 200},
+	}
+
+	for _, c := range checks {
+		if code := post(c.form, c.header); code != c.code {
+			t.Fatalf("Error: %s expected %d got %d\n", c.name, c.code, code)
+		}
+	}
+}
+
+func TestCSRFWithoutSession(t *testing.T) {
+	store := NewMapStore()
+
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Timeout = time.Hour
+	si.Store = store
+
+	csrf := sessions.NewCSRF(si)
+
+	h := si.GetHandler(csrf.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == "POST" {
+			w.Write([]byte("logged in"))
+			return
+		}
+
+		// A template writes the page up to the form before it asks for the token.
+		w.Write([]byte("<form>"))
+		token, err := csrf.Token(r)
+		if err != nil {
+			t.Fatalf("Error: getting csrf token:%s\n", err.Error())
+		}
+		w.Write([]byte(token + "</form>"))
+	})))
+
+	w := sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/login", nil))
+
+	cookie := w.Cookie()
+	if cookie == nil || cookie.Value == "" || cookie.MaxAge <= 0 {
+		t.Fatalf("Error: session cookie not set, got %+v\n", cookie)
+	}
+
+	if len(store.Records) != 1 || w.Session(t) == nil {
+		t.Fatalf("Error: expected the client's session in the store, got %d sessions\n", len(store.Records))
+	}
+
+	token := strings.TrimSuffix(strings.TrimPrefix(w.Body.String(), "<form>"), "</form>")
+
+	r := httptest.NewRequest("POST", "/login", nil)
+	r.AddCookie(cookie)
+	r.Header.Set("X-CSRF-Token", token)
+
+	w = sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK || w.Body.String() != "logged in" {
+		t.Fatalf("Error: expected 200 got %d %s\n", w.Code, w.Body.String())
+	}
+
+	if len(store.Records) != 1 {
+		t.Fatalf("Error: expected 1 session got %d\n", len(store.Records))
+	}
+}
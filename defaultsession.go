@@ -28,6 +28,19 @@ func NewDefaultSession() (*defaultSession, error) {
 	return session, err
 }
 
+// Create a session with an ID from generator rather than the default padded base32 ID.
+func NewSessionWithGenerator(generator IDGenerator) (*defaultSession, error) {
+	id, err := generator.Generate()
+	if err != nil {
+		return nil, err
+	}
+
+	session := new(defaultSession)
+	session.id = id
+	session.values = make(map[interface{}]interface{})
+	return session, nil
+}
+
 func (t *defaultSession) ID() (string, error) {
 	if t.id == "" {
 		k := make([]byte, 32)
@@ -120,5 +133,10 @@ func (t *defaultSession) GobDecode(data []byte) error {
 	t.id = decoded.ID
 	t.expires = decoded.Expires
 	t.values = decoded.Values
+
+	//Gob doesn't send empty maps.
+	if t.values == nil {
+		t.values = make(map[interface{}]interface{})
+	}
 	return nil
 }
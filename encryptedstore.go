@@ -0,0 +1,204 @@
+package sessions
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+)
+
+const (
+	encryptedkey     string = "sessions.encrypted"
+	encryptedversion byte   = 1
+)
+
+var (
+	ErrUnknownKey        = errors.New("sessions: session encrypted with unknown key")
+	ErrInvalidCiphertext = errors.New("sessions: invalid encrypted session")
+)
+
+// EncryptedStore encrypts sessions with AES-GCM before they reach the inner store.
+//
+// Each record carries the ID of the key it was encrypted with so keys can be rotated:
+// add the new key, make it current and keep the old keys until every session has been saved again
+// (sessions are always re-encrypted with the current key when saved).
+// Records written before the store was wrapped are returned as is and encrypted on their next save.
+//
+// The user a session is bound to (see BindUser) is only stored encrypted, so UserSessions has to decrypt
+// every session. Setting UserKey stores an HMAC of the user next to the ciphertext for the inner store
+// to index instead: lookups are fast and the user isn't readable, but sessions of the same user can be
+// linked to each other by anyone who can read the inner store.
+type EncryptedStore struct {
+	store   SessionStore
+	current string
+	keys    map[string]cipher.AEAD
+
+	// Key for the HMAC of the user stored for the inner store's index, nil doesn't store the user.
+	// Changing it loses the index until every session has been saved again.
+	UserKey []byte
+}
+
+// Create an EncryptedStore in front of store.
+// keys maps the key ID to an AES-128, AES-192 or AES-256 key, current is the ID of the key new records are encrypted with.
+func NewEncryptedStore(store SessionStore, current string, keys map[string][]byte) (*EncryptedStore, error) {
+	if len(current) > 255 {
+		return nil, errors.New("sessions: key id longer than 255 bytes")
+	}
+
+	if _, ok := keys[current]; !ok {
+		return nil, ErrUnknownKey
+	}
+
+	t := &EncryptedStore{
+		store:   store,
+		current: current,
+		keys:    make(map[string]cipher.AEAD),
+	}
+
+	for id, key := range keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		t.keys[id] = aead
+	}
+
+	return t, nil
+}
+
+func (t *EncryptedStore) Get(id string) (Session, error) {
+	s, err := t.store.Get(id)
+	if err != nil {
+		return s, err
+	}
+
+	return t.decrypt(s)
+}
+
+func (t *EncryptedStore) Set(s Session) error {
+	envelope, err := t.encrypt(s)
+	if err != nil {
+		return err
+	}
+
+	return t.store.Set(envelope)
+}
+
+func (t *EncryptedStore) Delete(id string) error {
+	return t.store.Delete(id)
+}
+
+// With a UserKey the inner store's index of the user's HMAC is used, otherwise every session is decrypted.
+func (t *EncryptedStore) UserSessions(user string) ([]string, error) {
+	if user == "" {
+		return nil, errors.New("sessions: empty user")
+	}
+
+	if t.UserKey == nil {
+		return scanUserSessions(t, user)
+	}
+	return userSessions(t.store, t.userHMAC(user))
+}
+
+func (t *EncryptedStore) userHMAC(user string) string {
+	mac := hmac.New(sha256.New, t.UserKey)
+	mac.Write([]byte(user))
+	return "hmac:" + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (t *EncryptedStore) All() ([]Session, error) {
+	all, err := t.store.All()
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range all {
+		all[i], err = t.decrypt(all[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return all, nil
+}
+
+// Record layout: version, key id length, key id, nonce, ciphertext.
+// The session ID is authenticated so a record can't be replayed under another ID.
+func (t *EncryptedStore) encrypt(s Session) (Session, error) {
+	id, err := s.ID()
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.GobEncode()
+	if err != nil {
+		return nil, err
+	}
+
+	aead := t.keys[t.current]
+
+	record := make([]byte, 0, 2+len(t.current)+aead.NonceSize()+len(plaintext)+aead.Overhead())
+	record = append(record, encryptedversion, byte(len(t.current)))
+	record = append(record, t.current...)
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	record = append(record, nonce...)
+	record = aead.Seal(record, nonce, plaintext, []byte(id))
+
+	user, err := SessionUser(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if user != "" && t.UserKey != nil {
+		return newEnvelope(s, encryptedkey, record, t.userHMAC(user))
+	}
+	return newEnvelope(s, encryptedkey, record, "")
+}
+
+func (t *EncryptedStore) decrypt(s Session) (Session, error) {
+	record, ok, err := openEnvelope(s, encryptedkey)
+	if err != nil || !ok {
+		return s, err
+	}
+
+	id, err := s.ID()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(record) < 2 || record[0] != encryptedversion || len(record) < 2+int(record[1]) {
+		return nil, ErrInvalidCiphertext
+	}
+
+	keyid := string(record[2 : 2+int(record[1])])
+	record = record[2+int(record[1]):]
+
+	aead, ok := t.keys[keyid]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	if len(record) < aead.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := aead.Open(nil, record[:aead.NonceSize()], record[aead.NonceSize():], []byte(id))
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return decodeSession(plaintext)
+}
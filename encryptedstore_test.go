@@ -0,0 +1,167 @@
+package sessions_test
+
+import (
+	"bytes"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"testing"
+)
+
+func TestEncryptedStore(t *testing.T) {
+	inner := NewMapStore()
+	keys := map[string][]byte{
+		"2015-01": bytes.Repeat([]byte{1}, 32),
+	}
+
+	es, err := sessions.NewEncryptedStore(inner, "2015-01", keys)
+	if err != nil {
+		t.Fatalf("Error: creating encrypted store:%s\n", err.Error())
+	}
+
+	var s sessions.Session
+	s, err = sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+
+	id, err := s.ID()
+	if err != nil {
+		t.Fatalf("Error: getting session id:%s\n", err.Error())
+	}
+
+	s.Set("Key", "Plaintext Value")
+	err = es.Set(s)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	if bytes.Contains(inner.Records[id], []byte("Plaintext Value")) {
+		t.Fatalf("Error: session value stored in plaintext\n")
+	}
+
+	s, err = es.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	v, err := s.Get("Key")
+	if err != nil {
+		t.Fatalf("Error: getting value from session:%s\n", err.Error())
+	}
+
+	if v != "Plaintext Value" {
+		t.Fatalf("Error: expected \"Plaintext Value\" received \"%v\"\n", v)
+	}
+
+	// Rotate the key, the old record should still be readable.
+	keys["2015-02"] = bytes.Repeat([]byte{2}, 32)
+	es, err = sessions.NewEncryptedStore(inner, "2015-02", keys)
+	if err != nil {
+		t.Fatalf("Error: creating encrypted store:%s\n", err.Error())
+	}
+
+	s, err = es.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session after rotation:%s\n", err.Error())
+	}
+
+	err = es.Set(s)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	// Once saved it's encrypted with the new key and the old one can go.
+	delete(keys, "2015-01")
+	es, err = sessions.NewEncryptedStore(inner, "2015-02", keys)
+	if err != nil {
+		t.Fatalf("Error: creating encrypted store:%s\n", err.Error())
+	}
+
+	s, err = es.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting re-encrypted session:%s\n", err.Error())
+	}
+
+	v, err = s.Get("Key")
+	if err != nil || v != "Plaintext Value" {
+		t.Fatalf("Error: expected \"Plaintext Value\" received \"%v\"\n", v)
+	}
+
+	all, err := es.All()
+	if err != nil {
+		t.Fatalf("Error: getting all sessions:%s\n", err.Error())
+	}
+
+	if len(all) != 1 {
+		t.Fatalf("Error: expected 1 session got %d\n", len(all))
+	}
+
+	// A store without the key can't read the record.
+	es, err = sessions.NewEncryptedStore(inner, "other", map[string][]byte{"other": bytes.Repeat([]byte{3}, 16)})
+	if err != nil {
+		t.Fatalf("Error: creating encrypted store:%s\n", err.Error())
+	}
+
+	_, err = es.Get(id)
+	if err != sessions.ErrUnknownKey {
+		t.Fatalf("Error: expected unknown key error got %v\n", err)
+	}
+}
+
+func TestEncryptedStoreConformance(t *testing.T) {
+	sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+		es, err := sessions.NewEncryptedStore(NewMapStore(), "key", map[string][]byte{"key": bytes.Repeat([]byte{1}, 32)})
+		if err != nil {
+			t.Fatalf("Error: creating encrypted store:%s\n", err.Error())
+		}
+		return es
+	})
+}
+
+func TestEncryptedStoreUser(t *testing.T) {
+	for _, userkey := range [][]byte{nil, []byte("user key")} {
+		inner := NewMapStore()
+		es, err := sessions.NewEncryptedStore(inner, "key", map[string][]byte{"key": bytes.Repeat([]byte{1}, 32)})
+		if err != nil {
+			t.Fatalf("Error: creating encrypted store:%s\n", err.Error())
+		}
+		es.UserKey = userkey
+
+		s, _ := sessions.NewDefaultSession()
+		sessions.BindUser(s, "alice@example.com")
+		id, _ := s.ID()
+
+		if err := es.Set(s); err != nil {
+			t.Fatalf("Error: saving session:%s\n", err.Error())
+		}
+
+		// The user never reaches the inner store in plaintext.
+		if bytes.Contains(inner.Records[id], []byte("alice@example.com")) {
+			t.Fatalf("Error: user stored in plaintext with user key %q\n", userkey)
+		}
+
+		raw, _ := inner.Get(id)
+		user, _ := sessions.SessionUser(raw)
+		if (userkey == nil) != (user == "") {
+			t.Fatalf("Error: unexpected user %q in inner store with user key %q\n", user, userkey)
+		}
+
+		ids, err := es.UserSessions("alice@example.com")
+		if err != nil {
+			t.Fatalf("Error: getting user sessions:%s\n", err.Error())
+		}
+
+		if len(ids) != 1 || ids[0] != id {
+			t.Fatalf("Error: expected [%s] got %v\n", id, ids)
+		}
+
+		got, err := es.Get(id)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		if user, _ := sessions.SessionUser(got); user != "alice@example.com" {
+			t.Fatalf("Error: expected alice@example.com got %q\n", user)
+		}
+	}
+}
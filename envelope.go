@@ -0,0 +1,57 @@
+package sessions
+
+// Stores that wrap other stores (encryption, compression...) can't change the
+// way the inner store encodes a session, so instead they hand it an envelope:
+// a session with the same ID and expiry whose only value is the transformed
+// encoding of the real session (and optionally a user binding so the inner
+// store can keep its user index).
+
+// Wrap the payload in a session the inner store can persist.
+// user is bound to the envelope for the inner store to index, empty leaves it unbound.
+func newEnvelope(s Session, key string, payload []byte, user string) (Session, error) {
+	id, err := s.ID()
+	if err != nil {
+		return nil, err
+	}
+
+	envelope := &defaultSession{
+		id:      id,
+		expires: s.Expiry(),
+		values:  map[interface{}]interface{}{key: payload},
+	}
+
+	if user != "" {
+		envelope.values[userkey] = user
+	}
+	return envelope, nil
+}
+
+// Get the payload out of an envelope.
+// ok is false when the session isn't an envelope (i.e. a new session or a record written before the wrapper was used).
+func openEnvelope(s Session, key string) (payload []byte, ok bool, err error) {
+	v, err := s.Get(key)
+	if err != nil {
+		return nil, false, err
+	}
+
+	payload, ok = v.([]byte)
+	return payload, ok, nil
+}
+
+// A copy of the session that later changes to s don't reach.
+func copySession(s Session) (Session, error) {
+	data, err := s.GobEncode()
+	if err != nil {
+		return nil, err
+	}
+	return decodeSession(data)
+}
+
+// Rebuild the session from the payload of an envelope.
+func decodeSession(data []byte) (Session, error) {
+	s := &defaultSession{}
+	if err := s.GobDecode(data); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
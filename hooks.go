@@ -0,0 +1,75 @@
+package sessions
+
+import (
+	"net/http"
+)
+
+// SessionEvent is passed to the lifecycle hooks.
+type SessionEvent struct {
+	ID string
+
+	// nil when the session is no longer available.
+	Session Session
+
+	// nil for events outside of the session's own request (i.e. the expiry collector or a revoke).
+	Request *http.Request
+
+	// Why the ID was rejected, only set for OnRejectID.
+	Err error
+}
+
+// Hooks are called as sessions move through their lifecycle, any can be nil.
+type Hooks struct {
+	// A new session has been saved for the first time.
+	OnCreate func(SessionEvent)
+
+	// An existing session has been loaded from the store.
+	OnLoad func(SessionEvent)
+
+	// A session has been saved to the store.
+	OnSave func(SessionEvent)
+
+	// A session has been destroyed (i.e. logout, revoked or evicted for MaxUserSessions).
+	// The event has the session as it was before it was destroyed.
+	OnDestroy func(SessionEvent)
+
+	// An expired session has been removed by the collector.
+	OnExpire func(SessionEvent)
+
+	// A client sent an ID that failed validation, the ID is as sent.
+	// A stream of these from one client suggests it's guessing IDs.
+	OnRejectID func(SessionEvent)
+}
+
+func (t *Hooks) fire(hook func(SessionEvent), id string, s Session, r *http.Request) {
+	if hook != nil {
+		hook(SessionEvent{
+			ID:      id,
+			Session: s,
+			Request: r,
+		})
+	}
+}
+
+// Delete the session from the store and fire OnDestroy, logout, revoke and eviction all go through here.
+// t can be nil.
+func (t *Hooks) destroy(store SessionStore, id string, s Session, r *http.Request) error {
+	if err := store.Delete(id); err != nil {
+		return err
+	}
+
+	if t != nil {
+		t.fire(t.OnDestroy, id, s, r)
+	}
+	return nil
+}
+
+func (t *Hooks) reject(id string, err error, r *http.Request) {
+	if t.OnRejectID != nil {
+		t.OnRejectID(SessionEvent{
+			ID:      id,
+			Request: r,
+			Err:     err,
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHooks(t *testing.T) {
+	store := NewMapStore()
+
+	si := sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Timeout = time.Hour
+	si.Store = store
+
+	events := make(map[string]int)
+	record := func(name string) func(sessions.SessionEvent) {
+		return func(e sessions.SessionEvent) {
+			if e.ID == "" || e.Session == nil || e.Request == nil {
+				t.Fatalf("Error: incomplete %s event %+v\n", name, e)
+			}
+			events[name]++
+		}
+	}
+
+	si.Hooks.OnCreate = record("create")
+	si.Hooks.OnLoad = record("load")
+	si.Hooks.OnSave = record("save")
+	si.Hooks.OnDestroy = func(e sessions.SessionEvent) {
+		record("destroy")(e)
+
+		// The session is as it was before it was destroyed.
+		if v, _ := e.Session.Get("Key"); v != "Value" {
+			t.Fatalf("Error: expected \"Value\" in destroyed session got \"%v\"\n", v)
+		}
+	}
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := si.GetSession(r)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		switch r.URL.Path {
+		case "/login":
+			s.Set("Key", "Value")
+		case "/logout":
+			if err := si.Destroy(w, r); err != nil {
+				t.Fatalf("Error: destroying session:%s\n", err.Error())
+			}
+		}
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/login", nil))
+
+	// The last cookie written has the new session.
+	cookies := w.Result().Cookies()
+	if len(cookies) == 0 || cookies[len(cookies)-1].Value == "" {
+		t.Fatalf("Error: session cookie not set\n")
+	}
+	cookie := cookies[len(cookies)-1]
+
+	for _, path := range []string{"/", "/logout"} {
+		r := httptest.NewRequest("GET", path, nil)
+		r.AddCookie(cookie)
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	expected := map[string]int{"create": 1, "load": 2, "save": 2, "destroy": 1}
+	for name, n := range expected {
+		if events[name] != n {
+			t.Fatalf("Error: expected %d %s events got %d\n", n, name, events[name])
+		}
+	}
+
+	if len(store.Records) != 0 {
+		t.Fatalf("Error: destroyed session still in the store\n")
+	}
+}
@@ -0,0 +1,161 @@
+package sessions
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strings"
+)
+
+// IDGenerator creates session IDs and recognises the IDs it creates.
+type IDGenerator interface {
+	Generate() (string, error)
+
+	// Whether id is in the format Generate produces, used to turn away malformed IDs before they reach the store.
+	Valid(id string) bool
+}
+
+const (
+	defaultidbytes int = 32
+)
+
+// Base32Generator creates random IDs in unpadded base32 (A-Z, 2-7).
+type Base32Generator struct {
+	// Random bytes in the ID, defaults to 32.
+	Bytes int
+}
+
+func (t Base32Generator) Generate() (string, error) {
+	k, err := randomBytes(t.Bytes)
+	if err != nil {
+		return "", err
+	}
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k), nil
+}
+
+func (t Base32Generator) Valid(id string) bool {
+	k, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(id)
+	return err == nil && len(k) == idBytes(t.Bytes)
+}
+
+// Base64Generator creates random IDs in unpadded URL safe base64 (A-Z, a-z, 0-9, '-' and '_').
+type Base64Generator struct {
+	// Random bytes in the ID, defaults to 32.
+	Bytes int
+}
+
+func (t Base64Generator) Generate() (string, error) {
+	k, err := randomBytes(t.Bytes)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(k), nil
+}
+
+func (t Base64Generator) Valid(id string) bool {
+	k, err := base64.RawURLEncoding.DecodeString(id)
+	return err == nil && len(k) == idBytes(t.Bytes)
+}
+
+// UUIDv4Generator creates random (version 4) UUIDs.
+type UUIDv4Generator struct{}
+
+func (t UUIDv4Generator) Generate() (string, error) {
+	u, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+	return formatUUID(u, 4), nil
+}
+
+func (t UUIDv4Generator) Valid(id string) bool {
+	return validUUID(id, 4)
+}
+
+// UUIDv7Generator creates time ordered (version 7) UUIDs, sessions created together sort together.
+// The creation time can be read back from the ID.
+type UUIDv7Generator struct {
+	// The time put in the UUID, defaults to SystemClock.
+	Clock Clock
+}
+
+func (t UUIDv7Generator) Generate() (string, error) {
+	u, err := randomBytes(16)
+	if err != nil {
+		return "", err
+	}
+
+	// The first 48 bits are the unix time in milliseconds.
+	ts := make([]byte, 8)
+	binary.BigEndian.PutUint64(ts, uint64(now(t.Clock).UnixMilli()))
+	copy(u[:6], ts[2:])
+
+	return formatUUID(u, 7), nil
+}
+
+func (t UUIDv7Generator) Valid(id string) bool {
+	return validUUID(id, 7)
+}
+
+// PrefixedGenerator puts a fixed prefix in front of another generator's IDs, i.e. to route sessions by ID.
+type PrefixedGenerator struct {
+	Prefix    string
+	Generator IDGenerator
+}
+
+func (t PrefixedGenerator) Generate() (string, error) {
+	id, err := t.Generator.Generate()
+	if err != nil {
+		return "", err
+	}
+	return t.Prefix + id, nil
+}
+
+func (t PrefixedGenerator) Valid(id string) bool {
+	return strings.HasPrefix(id, t.Prefix) && t.Generator.Valid(id[len(t.Prefix):])
+}
+
+func idBytes(n int) int {
+	if n <= 0 {
+		return defaultidbytes
+	}
+	return n
+}
+
+func randomBytes(n int) ([]byte, error) {
+	k := make([]byte, idBytes(n))
+	if _, err := io.ReadFull(rand.Reader, k); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Set the version and (RFC 4122) variant bits and format as xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx.
+func formatUUID(u []byte, version byte) string {
+	u[6] = (u[6] & 0x0f) | version<<4
+	u[8] = (u[8] & 0x3f) | 0x80
+
+	h := hex.EncodeToString(u)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+func validUUID(id string, version byte) bool {
+	if len(id) != 36 || id[8] != '-' || id[13] != '-' || id[18] != '-' || id[23] != '-' {
+		return false
+	}
+
+	h := id[:8] + id[9:13] + id[14:18] + id[19:23] + id[24:]
+	if strings.ToLower(h) != h {
+		return false
+	}
+
+	u, err := hex.DecodeString(h)
+	if err != nil {
+		return false
+	}
+
+	return u[6]>>4 == version && u[8]&0xc0 == 0x80
+}
@@ -0,0 +1,138 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDGenerators(t *testing.T) {
+	generators := map[string]sessions.IDGenerator{
+		"base32":   sessions.Base32Generator{},
+		"base32-8": sessions.Base32Generator{Bytes: 8},
+		"base64":   sessions.Base64Generator{},
+		"uuidv4":   sessions.UUIDv4Generator{},
+		"uuidv7":   sessions.UUIDv7Generator{},
+		"prefixed": sessions.PrefixedGenerator{Prefix: "eu1.", Generator: sessions.Base64Generator{Bytes: 16}},
+	}
+
+	for name, g := range generators {
+		id, err := g.Generate()
+		if err != nil {
+			t.Fatalf("Error: generating %s id:%s\n", name, err.Error())
+		}
+
+		if !g.Valid(id) {
+			t.Fatalf("Error: %s id %s not valid\n", name, id)
+		}
+
+		if strings.ContainsAny(id, "=+/") {
+			t.Fatalf("Error: %s id %s isn't cookie safe\n", name, id)
+		}
+
+		other, err := g.Generate()
+		if err != nil {
+			t.Fatalf("Error: generating %s id:%s\n", name, err.Error())
+		}
+
+		if other == id {
+			t.Fatalf("Error: %s generated %s twice\n", name, id)
+		}
+
+		for _, bad := range []string{"", id[1:], id + "A", strings.Repeat("!", len(id)), TESTSESSIONID} {
+			if g.Valid(bad) {
+				t.Fatalf("Error: %s accepted %s\n", name, bad)
+			}
+		}
+	}
+
+	if (sessions.UUIDv4Generator{}).Valid("6ba7b810-9dad-11d1-80b4-00c04fd430c8") {
+		t.Fatalf("Error: version 1 UUID accepted as version 4\n")
+	}
+}
+
+func TestUUIDv7Order(t *testing.T) {
+	clock := sessionstest.NewFakeClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	g := sessions.UUIDv7Generator{Clock: clock}
+
+	first, err := g.Generate()
+	if err != nil {
+		t.Fatalf("Error: generating id:%s\n", err.Error())
+	}
+
+	clock.Advance(time.Millisecond)
+
+	second, err := g.Generate()
+	if err != nil {
+		t.Fatalf("Error: generating id:%s\n", err.Error())
+	}
+
+	if !(first < second) {
+		t.Fatalf("Error: expected %s to sort before %s\n", first, second)
+	}
+
+	if !strings.HasPrefix(first, "016f5e66-e800-7") {
+		t.Fatalf("Error: unexpected timestamp in %s\n", first)
+	}
+}
+
+func TestSessionInfoIDGenerator(t *testing.T) {
+	store := NewMapStore()
+
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Timeout = time.Hour
+	si.Store = store
+	si.IDGenerator = sessions.UUIDv4Generator{}
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := si.GetSession(r)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+		s.Set("Key", "Value")
+	}))
+
+	// A session saved under an ID the generator wouldn't produce isn't loaded.
+	old, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	old.Set("Key", "Old")
+	old.SetExpiry(time.Now().Add(time.Hour))
+	store.Set(old)
+
+	oldid, err := old.ID()
+	if err != nil {
+		t.Fatalf("Error: getting session id:%s\n", err.Error())
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: si.Cookie.Name, Value: oldid})
+
+	w := sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, r)
+
+	cookie := w.Cookie()
+	if cookie == nil || !si.IDGenerator.Valid(cookie.Value) {
+		t.Fatalf("Error: expected a new UUID session got %+v\n", cookie)
+	}
+
+	s := w.Session(t)
+	if s == nil {
+		t.Fatalf("Error: session not saved\n")
+	}
+
+	v, err := s.Get("Key")
+	if err != nil {
+		t.Fatalf("Error: getting value from session:%s\n", err.Error())
+	}
+
+	if v != "Value" {
+		t.Fatalf("Error: expected Value got %v\n", v)
+	}
+}
@@ -0,0 +1,104 @@
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrIDTooLong   = errors.New("sessions: session id too long")
+	ErrIDTooShort  = errors.New("sessions: session id too short")
+	ErrIDCharset   = errors.New("sessions: session id contains invalid characters")
+	ErrIDSignature = errors.New("sessions: session id signature invalid")
+	ErrIDFormat    = errors.New("sessions: session id not in the generator's format")
+)
+
+const (
+	defaultmaxidlength int = 256
+)
+
+// IDValidation is what a session ID sent by a client has to pass before it's looked up in the store.
+type IDValidation struct {
+	// Longest ID accepted, defaults to 256.
+	MaxLength int
+
+	// Shortest ID accepted.
+	MinLength int
+
+	// The characters IDs may contain, empty allows any.
+	Charset string
+
+	// HMAC-SHA256 keys to sign IDs with, IDs are then sent to the client as id.signature.
+	// The first key signs, all are tried when verifying so keys can be rotated. Empty doesn't sign.
+	SigningKeys [][]byte
+}
+
+// The value to send to the client for id.
+func (t *IDValidation) Sign(id string) string {
+	if len(t.SigningKeys) == 0 {
+		return id
+	}
+	return id + "." + signID(t.SigningKeys[0], id)
+}
+
+// Check a value sent by the client and return the session ID in it.
+func (t *IDValidation) Verify(value string) (string, error) {
+	max := t.MaxLength
+	if max <= 0 {
+		max = defaultmaxidlength
+	}
+
+	id := value
+	if len(t.SigningKeys) > 0 {
+		// Refuse oversized values before spending time on the signature.
+		if len(value) > max+1+base64.RawURLEncoding.EncodedLen(sha256.Size) {
+			return "", ErrIDTooLong
+		}
+
+		i := strings.LastIndexByte(value, '.')
+		if i < 0 {
+			return "", ErrIDSignature
+		}
+
+		id = value[:i]
+		if !t.verifySignature(id, value[i+1:]) {
+			return "", ErrIDSignature
+		}
+	}
+
+	if len(id) > max {
+		return "", ErrIDTooLong
+	}
+
+	if len(id) < t.MinLength {
+		return "", ErrIDTooShort
+	}
+
+	if t.Charset != "" {
+		for _, r := range id {
+			if !strings.ContainsRune(t.Charset, r) {
+				return "", ErrIDCharset
+			}
+		}
+	}
+
+	return id, nil
+}
+
+func (t *IDValidation) verifySignature(id, signature string) bool {
+	for _, key := range t.SigningKeys {
+		if hmac.Equal([]byte(signID(key, id)), []byte(signature)) {
+			return true
+		}
+	}
+	return false
+}
+
+func signID(key []byte, id string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(id))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,116 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDValidation(t *testing.T) {
+	v := sessions.IDValidation{
+		MinLength: 8,
+		Charset:   "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567",
+	}
+
+	checks := map[string]error{
+		"ABCDEFGH":                 nil,
+		"ABCDEFG":                  sessions.ErrIDTooShort,
+		"ABCDEFGH=":                sessions.ErrIDCharset,
+		"ERROR\x00AB":              sessions.ErrIDCharset,
+		strings.Repeat("A", 257):   sessions.ErrIDTooLong,
+		strings.Repeat("A", 1<<20): sessions.ErrIDTooLong,
+	}
+
+	for value, want := range checks {
+		if _, err := v.Verify(value); err != want {
+			t.Fatalf("Error: expected %v for %.16q got %v\n", want, value, err)
+		}
+	}
+
+	old := []byte("old key")
+	v.SigningKeys = [][]byte{old}
+	signed := v.Sign("ABCDEFGH")
+
+	// Rotating keys still accepts IDs signed with the old key.
+	v.SigningKeys = [][]byte{[]byte("new key"), old}
+
+	id, err := v.Verify(signed)
+	if err != nil {
+		t.Fatalf("Error: verifying signed id:%s\n", err.Error())
+	}
+
+	if id != "ABCDEFGH" {
+		t.Fatalf("Error: expected ABCDEFGH got %s\n", id)
+	}
+
+	for _, value := range []string{"ABCDEFGH", "ABCDEFGI" + signed[8:], signed + "A", v.Sign("ABCDEFGH")[:9]} {
+		if _, err := v.Verify(value); err != sessions.ErrIDSignature {
+			t.Fatalf("Error: expected signature error for %s got %v\n", value, err)
+		}
+	}
+}
+
+func TestSessionInfoRejectID(t *testing.T) {
+	store := NewMapStore()
+
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Timeout = time.Hour
+	si.Store = store
+	si.IDValidation.SigningKeys = [][]byte{[]byte("key")}
+
+	rejected := make([]error, 0)
+	si.Hooks.OnRejectID = func(e sessions.SessionEvent) {
+		if e.Request == nil || e.Session != nil {
+			t.Fatalf("Error: unexpected reject event %+v\n", e)
+		}
+		rejected = append(rejected, e.Err)
+	}
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := si.GetSession(r)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+		s.Set("Key", "Value")
+	}))
+
+	// Signed IDs are accepted and the cookie written back is signed.
+	r := sessionstest.NewRequest(t, si, "GET", "/", nil, map[interface{}]interface{}{"Key": "Value"})
+	sent, _ := r.Cookie(si.Cookie.Name)
+
+	w := sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, r)
+
+	if w.Cookie() == nil || w.Cookie().Value != sent.Value {
+		t.Fatalf("Error: expected cookie %s got %+v\n", sent.Value, w.Cookie())
+	}
+
+	if len(rejected) != 0 {
+		t.Fatalf("Error: valid id rejected %v\n", rejected)
+	}
+
+	// The raw ID, without its signature, is a new session.
+	id, err := si.IDValidation.Verify(sent.Value)
+	if err != nil {
+		t.Fatalf("Error: verifying cookie:%s\n", err.Error())
+	}
+
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: si.Cookie.Name, Value: id})
+
+	w = sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, r)
+
+	if w.Cookie() == nil || w.Cookie().Value == sent.Value {
+		t.Fatalf("Error: expected a new session got %+v\n", w.Cookie())
+	}
+
+	if len(rejected) != 1 || rejected[0] != sessions.ErrIDSignature {
+		t.Fatalf("Error: expected signature rejection got %v\n", rejected)
+	}
+}
@@ -0,0 +1,71 @@
+// Package storespec opens the bundled session stores from a "type:path" spec for the command line tools.
+package storespec
+
+import (
+	"errors"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/boltsessionstore"
+	"github.com/d2g/sessions/unqlitesessionstore"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+const Usage = "bolt:/path/to/file.db or unqlite:/path/to/file.db"
+
+type Mode int
+
+const (
+	// The file must exist and is only read.
+	ReadOnly Mode = iota
+
+	// The file must exist.
+	ReadWrite
+
+	// The file is created if it doesn't exist.
+	Create
+)
+
+// Open the store described by spec, the returned Closer releases the underlying database.
+// Unless mode is Create a missing file is an error rather than a new, empty store.
+func Open(spec string, mode Mode) (sessions.SessionStore, io.Closer, error) {
+	i := strings.Index(spec, ":")
+	if i < 0 {
+		return nil, nil, errors.New("invalid store \"" + spec + "\" expected " + Usage)
+	}
+
+	kind, path := spec[:i], spec[i+1:]
+
+	if mode != Create {
+		if _, err := os.Stat(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	switch kind {
+	case "bolt":
+		store, err := boltsessionstore.Open(path, &boltsessionstore.Options{Timeout: time.Second, ReadOnly: mode == ReadOnly})
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, store, nil
+
+	case "unqlite":
+		m := unqlitesessionstore.OpenCreate
+		switch mode {
+		case ReadOnly:
+			m = unqlitesessionstore.OpenReadOnly
+		case ReadWrite:
+			m = unqlitesessionstore.OpenReadWrite
+		}
+
+		store, err := unqlitesessionstore.Open(path, m)
+		if err != nil {
+			return nil, nil, err
+		}
+		return store, store, nil
+	}
+
+	return nil, nil, errors.New("unknown store type \"" + kind + "\" expected " + Usage)
+}
@@ -0,0 +1,57 @@
+package storespec_test
+
+import (
+	"github.com/boltdb/bolt"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/internal/storespec"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "typo.db")
+
+	for _, mode := range []storespec.Mode{storespec.ReadOnly, storespec.ReadWrite} {
+		if _, _, err := storespec.Open("bolt:"+path, mode); err == nil {
+			t.Fatalf("Error: expected opening a missing file with mode %d to fail\n", mode)
+		}
+
+		if _, err := os.Stat(path); err == nil {
+			t.Fatalf("Error: missing file created with mode %d\n", mode)
+		}
+	}
+
+	store, closer, err := storespec.Open("bolt:"+path, storespec.Create)
+	if err != nil {
+		t.Fatalf("Error: creating store:%s\n", err.Error())
+	}
+
+	s, _ := sessions.NewDefaultSession()
+	s.Set("Key", "Value")
+	id, _ := s.ID()
+	if err := store.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+	closer.Close()
+
+	// Read only stores can't be written to.
+	store, closer, err = storespec.Open("bolt:"+path, storespec.ReadOnly)
+	if err != nil {
+		t.Fatalf("Error: opening store read only:%s\n", err.Error())
+	}
+	defer closer.Close()
+
+	got, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if v, _ := got.Get("Key"); v != "Value" {
+		t.Fatalf("Error: expected \"Value\" got \"%v\"\n", v)
+	}
+
+	if err := store.Delete(id); err != bolt.ErrDatabaseReadOnly {
+		t.Fatalf("Error: expected read only error got %v\n", err)
+	}
+}
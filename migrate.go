@@ -0,0 +1,210 @@
+package sessions
+
+import (
+	"errors"
+)
+
+var (
+	ErrMigrationVerify = errors.New("sessions: migrated session missing from destination")
+)
+
+type MigrateOptions struct {
+	// Copy sessions that have already expired.
+	IncludeExpired bool
+
+	// Check every migrated session can be read back from the destination.
+	Verify bool
+
+	// Called after each session is copied.
+	Progress func(MigrateResult)
+
+	// The time expired sessions are judged against, defaults to SystemClock.
+	Clock Clock
+}
+
+type MigrateResult struct {
+	// Sessions read from the source.
+	Read int
+
+	// Sessions written to the destination.
+	Migrated int
+
+	// Expired sessions that were skipped.
+	Expired int
+
+	// Migrated sessions read back from the destination.
+	Verified int
+
+	// Sessions deleted from the source (i.e. logged out) after Migrate listed them.
+	Deleted int
+
+	// Sessions skipped because the destination already had them with the same or a later expiry.
+	Existing int
+}
+
+// Copy the sessions in src to dst.
+// The source isn't modified so it can be run again, i.e. while dst is written by a DualWriteStore.
+//
+// Each session is read from src again just before it's copied, so sessions changed or deleted since
+// the listing aren't copied stale or brought back. Sessions already in dst are only overwritten when
+// src's copy expires later, i.e. it's been used since dst's copy was written.
+func Migrate(dst, src SessionStore, opts MigrateOptions) (MigrateResult, error) {
+	result := MigrateResult{}
+
+	all, err := src.All()
+	if err != nil {
+		return result, err
+	}
+
+	now := now(opts.Clock)
+	migrated := make([]string, 0, len(all))
+
+	for _, listed := range all {
+		result.Read++
+
+		id, err := listed.ID()
+		if err != nil {
+			return result, err
+		}
+
+		s, ok, err := getExisting(src, id)
+		if err != nil {
+			return result, err
+		}
+
+		if !ok {
+			result.Deleted++
+			continue
+		}
+
+		if !opts.IncludeExpired && s.Expiry().Before(now) {
+			result.Expired++
+			continue
+		}
+
+		existing, ok, err := getExisting(dst, id)
+		if err != nil {
+			return result, err
+		}
+
+		if ok && !existing.Expiry().Before(s.Expiry()) {
+			result.Existing++
+			continue
+		}
+
+		if err := dst.Set(s); err != nil {
+			return result, err
+		}
+
+		migrated = append(migrated, id)
+		result.Migrated++
+
+		if opts.Progress != nil {
+			opts.Progress(result)
+		}
+	}
+
+	if !opts.Verify {
+		return result, nil
+	}
+
+	for _, id := range migrated {
+		_, ok, err := getExisting(dst, id)
+		if err != nil {
+			return result, err
+		}
+
+		if !ok {
+			return result, ErrMigrationVerify
+		}
+		result.Verified++
+	}
+
+	return result, nil
+}
+
+// Get a session, ok is false when the ID isn't in the store.
+func getExisting(store SessionStore, id string) (Session, bool, error) {
+	s, err := store.Get(id)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Stores hand back a new session when the ID isn't found.
+	got, err := s.ID()
+	if err != nil {
+		return nil, false, err
+	}
+	return s, got == id, nil
+}
+
+// DualWriteStore writes to both the store being migrated from and the store being migrated to.
+//
+// Used during a cut-over so sessions changed while Migrate is running aren't lost:
+// reads prefer the new store and fall back to the old one for sessions that haven't been migrated yet.
+type DualWriteStore struct {
+	Old SessionStore
+	New SessionStore
+}
+
+func (t *DualWriteStore) Get(id string) (Session, error) {
+	s, err := t.New.Get(id)
+	if err != nil || id == "" {
+		return s, err
+	}
+
+	if got, err := s.ID(); err == nil && got == id {
+		return s, nil
+	}
+
+	return t.Old.Get(id)
+}
+
+func (t *DualWriteStore) Set(s Session) error {
+	if err := t.Old.Set(s); err != nil {
+		return err
+	}
+	return t.New.Set(s)
+}
+
+func (t *DualWriteStore) Delete(id string) error {
+	if err := t.Old.Delete(id); err != nil {
+		return err
+	}
+	return t.New.Delete(id)
+}
+
+// All sessions from both stores, where a session is in both the new store's copy is returned.
+func (t *DualWriteStore) All() ([]Session, error) {
+	all, err := t.New.All()
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range all {
+		id, err := s.ID()
+		if err != nil {
+			return nil, err
+		}
+		seen[id] = true
+	}
+
+	old, err := t.Old.All()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range old {
+		id, err := s.ID()
+		if err != nil {
+			return nil, err
+		}
+
+		if !seen[id] {
+			all = append(all, s)
+		}
+	}
+
+	return all, nil
+}
@@ -0,0 +1,203 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"testing"
+	"time"
+)
+
+func TestMigrate(t *testing.T) {
+	src := NewMapStore()
+	dst := NewMapStore()
+
+	for i := 0; i < 10; i++ {
+		s, err := sessions.NewDefaultSession()
+		if err != nil {
+			t.Fatalf("Error: creating new session:%s\n", err.Error())
+		}
+
+		if i%2 == 0 {
+			s.SetExpiry(time.Now().Add(time.Hour))
+		} else {
+			s.SetExpiry(time.Now().Add(-time.Hour))
+		}
+		src.Set(s)
+	}
+
+	progress := 0
+	result, err := sessions.Migrate(dst, src, sessions.MigrateOptions{
+		Verify: true,
+		Progress: func(sessions.MigrateResult) {
+			progress++
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error: migrating:%s\n", err.Error())
+	}
+
+	expected := sessions.MigrateResult{Read: 10, Migrated: 5, Expired: 5, Verified: 5}
+	if result != expected {
+		t.Fatalf("Error: expected %+v got %+v\n", expected, result)
+	}
+
+	if len(dst.Records) != 5 || progress != 5 {
+		t.Fatalf("Error: expected 5 sessions migrated got %d\n", len(dst.Records))
+	}
+
+	result, err = sessions.Migrate(NewMapStore(), src, sessions.MigrateOptions{IncludeExpired: true})
+	if err != nil {
+		t.Fatalf("Error: migrating:%s\n", err.Error())
+	}
+
+	if result.Migrated != 10 {
+		t.Fatalf("Error: expected 10 sessions migrated got %d\n", result.Migrated)
+	}
+
+	// Nothing written to the destination can be read back.
+	_, err = sessions.Migrate(&MockStore{}, src, sessions.MigrateOptions{Verify: true})
+	if err != sessions.ErrMigrationVerify {
+		t.Fatalf("Error: expected verify error got %v\n", err)
+	}
+}
+
+func TestDualWriteStore(t *testing.T) {
+	old := NewMapStore()
+	dws := &sessions.DualWriteStore{
+		Old: old,
+		New: NewMapStore(),
+	}
+
+	legacy, _ := sessions.NewDefaultSession()
+	legacy.Set("Key", "Legacy")
+	legacyid, _ := legacy.ID()
+	old.Set(legacy)
+
+	// Not migrated yet, read from the old store.
+	s, err := dws.Get(legacyid)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if v, _ := s.Get("Key"); v != "Legacy" {
+		t.Fatalf("Error: expected \"Legacy\" received \"%v\"\n", v)
+	}
+
+	s.Set("Key", "Updated")
+	err = dws.Set(s)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	fresh, _ := sessions.NewDefaultSession()
+	err = dws.Set(fresh)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	all, err := dws.All()
+	if err != nil {
+		t.Fatalf("Error: getting all sessions:%s\n", err.Error())
+	}
+
+	if len(all) != 2 || len(old.Records) != 2 {
+		t.Fatalf("Error: expected 2 sessions got %d\n", len(all))
+	}
+
+	err = dws.Delete(legacyid)
+	if err != nil {
+		t.Fatalf("Error: deleting session:%s\n", err.Error())
+	}
+
+	if len(old.Records) != 1 {
+		t.Fatalf("Error: session not deleted from old store\n")
+	}
+}
+
+func TestDualWriteStoreConformance(t *testing.T) {
+	sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+		return &sessions.DualWriteStore{
+			Old: NewMapStore(),
+			New: NewMapStore(),
+		}
+	})
+}
+
+// A MapStore that runs afterAll once All has listed the sessions.
+type ListedStore struct {
+	*MapStore
+	afterAll func()
+}
+
+func (t *ListedStore) All() ([]sessions.Session, error) {
+	all, err := t.MapStore.All()
+	if t.afterAll != nil {
+		t.afterAll()
+	}
+	return all, err
+}
+
+func TestMigrateDuringDualWrite(t *testing.T) {
+	src := &ListedStore{MapStore: NewMapStore()}
+	dst := NewMapStore()
+	dws := &sessions.DualWriteStore{Old: src, New: dst}
+
+	newSession := func(value string, expiry time.Time) (sessions.Session, string) {
+		s, _ := sessions.NewDefaultSession()
+		s.Set("Key", value)
+		s.SetExpiry(expiry)
+		id, _ := s.ID()
+		return s, id
+	}
+
+	loggedout, loggedoutid := newSession("LoggedOut", time.Now().Add(time.Hour))
+	src.Set(loggedout)
+
+	updated, updatedid := newSession("Old", time.Now().Add(time.Hour))
+	src.Set(updated)
+
+	// Already copied by an earlier run, but used in the source since.
+	stale, staleid := newSession("Stale", time.Now().Add(time.Hour))
+	dst.Set(stale)
+	stale.Set("Key", "Fresh")
+	stale.SetExpiry(time.Now().Add(2 * time.Hour))
+	src.Set(stale)
+
+	// Changes made through the DualWriteStore after Migrate has listed the source.
+	src.afterAll = func() {
+		dws.Delete(loggedoutid)
+
+		updated.Set("Key", "New")
+		updated.SetExpiry(time.Now().Add(2 * time.Hour))
+		dws.Set(updated)
+	}
+
+	result, err := sessions.Migrate(dst, src, sessions.MigrateOptions{Verify: true})
+	if err != nil {
+		t.Fatalf("Error: migrating:%s\n", err.Error())
+	}
+
+	if result.Deleted != 1 || result.Existing != 1 || result.Migrated != 1 {
+		t.Fatalf("Error: unexpected result %+v\n", result)
+	}
+
+	if _, ok := dst.Records[loggedoutid]; ok {
+		t.Fatalf("Error: deleted session brought back\n")
+	}
+
+	checks := map[string]string{
+		updatedid: "New",
+		staleid:   "Fresh",
+	}
+
+	for id, want := range checks {
+		s, err := dst.Get(id)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		if v, _ := s.Get("Key"); v != want {
+			t.Fatalf("Error: expected %s got %v\n", want, v)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package sessions
+
+import (
+	"time"
+)
+
+// QuarantinedRecord is a record a store couldn't decode.
+// All skips them and moves them out of the way, they're kept so they can be inspected or recovered.
+type QuarantinedRecord struct {
+	ID string
+
+	// The raw record.
+	Data []byte
+
+	// Why it couldn't be decoded.
+	Error string
+
+	// When it was quarantined.
+	Time time.Time
+}
+
+// Quarantine is implemented by stores that quarantine undecodable records.
+type Quarantine interface {
+	// The records that can't be decoded and haven't been quarantined yet, without moving them.
+	Corrupt() ([]QuarantinedRecord, error)
+
+	// Move the records that can't be decoded to the quarantine, returns the number moved.
+	QuarantineCorrupt() (int, error)
+
+	// All the quarantined records.
+	Quarantined() ([]QuarantinedRecord, error)
+
+	// Try to decode the record again (i.e. after registering a missing gob type),
+	// on success the session is restored to the store and returned.
+	RetryQuarantined(id string) (Session, error)
+
+	// Delete all quarantined records, returns the number deleted.
+	PurgeQuarantine() (int, error)
+}
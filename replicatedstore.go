@@ -0,0 +1,215 @@
+package sessions
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+type ReplicationMode int
+
+const (
+	// Secondaries are written before Set/Delete return.
+	SyncReplication ReplicationMode = iota
+
+	// Secondaries are written in the background.
+	AsyncReplication
+)
+
+var (
+	ErrReplicationQueueFull = errors.New("sessions: replication queue full")
+)
+
+// ReplicationEvent reports the outcome of writing a change to a secondary.
+type ReplicationEvent struct {
+	// Index of the secondary.
+	Secondary int
+
+	// The session ID written or deleted.
+	ID string
+
+	// Time between the change reaching the primary and the secondary.
+	Lag time.Duration
+
+	// Why the secondary couldn't be written, nil on success.
+	Err error
+}
+
+// ReplicatedStore writes to a primary store and copies every change to the secondaries.
+//
+// Reads go to the primary and fail over to the secondaries (in order) when the primary returns an error.
+// Failing to write a secondary doesn't fail the Set or Delete, it's reported through OnReplication.
+type ReplicatedStore struct {
+	primary     SessionStore
+	secondaries []SessionStore
+	mode        ReplicationMode
+
+	// Called after every write to a secondary.
+	OnReplication func(ReplicationEvent)
+
+	queues []chan replicationOp
+	wg     sync.WaitGroup
+
+	// Held for reading while changes are queued so Close can't close the queues under them.
+	mu     sync.RWMutex
+	closed bool
+}
+
+type replicationOp struct {
+	id      string
+	encoded []byte // nil for a delete
+	at      time.Time
+}
+
+// Create a ReplicatedStore.
+// In AsyncReplication mode up to queueSize changes are buffered per secondary, further changes are dropped and reported.
+func NewReplicatedStore(mode ReplicationMode, queueSize int, primary SessionStore, secondaries ...SessionStore) *ReplicatedStore {
+	t := &ReplicatedStore{
+		primary:     primary,
+		secondaries: secondaries,
+		mode:        mode,
+	}
+
+	if mode == AsyncReplication {
+		for i := range secondaries {
+			q := make(chan replicationOp, queueSize)
+			t.queues = append(t.queues, q)
+
+			t.wg.Add(1)
+			go t.replicate(i, q)
+		}
+	}
+
+	return t
+}
+
+func (t *ReplicatedStore) Get(id string) (Session, error) {
+	s, err := t.primary.Get(id)
+	if err == nil {
+		return s, nil
+	}
+
+	for _, secondary := range t.secondaries {
+		if s, serr := secondary.Get(id); serr == nil {
+			return s, nil
+		}
+	}
+	return nil, err
+}
+
+func (t *ReplicatedStore) Set(s Session) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return ErrStoreClosed
+	}
+
+	if err := t.primary.Set(s); err != nil {
+		return err
+	}
+
+	id, err := s.ID()
+	if err != nil {
+		return err
+	}
+
+	encoded, err := s.GobEncode()
+	if err != nil {
+		return err
+	}
+
+	t.distribute(replicationOp{id, encoded, time.Now()})
+	return nil
+}
+
+func (t *ReplicatedStore) Delete(id string) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if t.closed {
+		return ErrStoreClosed
+	}
+
+	if err := t.primary.Delete(id); err != nil {
+		return err
+	}
+
+	t.distribute(replicationOp{id, nil, time.Now()})
+	return nil
+}
+
+func (t *ReplicatedStore) All() ([]Session, error) {
+	all, err := t.primary.All()
+	if err == nil {
+		return all, nil
+	}
+
+	for _, secondary := range t.secondaries {
+		if all, serr := secondary.All(); serr == nil {
+			return all, nil
+		}
+	}
+	return nil, err
+}
+
+// Wait for queued changes to reach the secondaries and stop replicating.
+// Sets and Deletes after Close return ErrStoreClosed.
+func (t *ReplicatedStore) Close() error {
+	t.mu.Lock()
+	if !t.closed {
+		t.closed = true
+		for _, q := range t.queues {
+			close(q)
+		}
+	}
+	t.mu.Unlock()
+
+	t.wg.Wait()
+	return nil
+}
+
+func (t *ReplicatedStore) distribute(op replicationOp) {
+	for i := range t.secondaries {
+		if t.mode != AsyncReplication {
+			t.apply(i, op)
+			continue
+		}
+
+		select {
+		case t.queues[i] <- op:
+		default:
+			t.report(ReplicationEvent{i, op.id, time.Since(op.at), ErrReplicationQueueFull})
+		}
+	}
+}
+
+func (t *ReplicatedStore) replicate(i int, q chan replicationOp) {
+	defer t.wg.Done()
+
+	for op := range q {
+		t.apply(i, op)
+	}
+}
+
+func (t *ReplicatedStore) apply(i int, op replicationOp) {
+	var err error
+
+	if op.encoded == nil {
+		err = t.secondaries[i].Delete(op.id)
+	} else {
+		var s Session
+		s, err = decodeSession(op.encoded)
+		if err == nil {
+			err = t.secondaries[i].Set(s)
+		}
+	}
+
+	t.report(ReplicationEvent{i, op.id, time.Since(op.at), err})
+}
+
+func (t *ReplicatedStore) report(e ReplicationEvent) {
+	if t.OnReplication != nil {
+		t.OnReplication(e)
+	}
+}
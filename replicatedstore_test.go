@@ -0,0 +1,129 @@
+package sessions_test
+
+import (
+	"errors"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"testing"
+)
+
+// A store that's down.
+type DownStore struct{}
+
+func (t *DownStore) Get(id string) (sessions.Session, error) {
+	return nil, errors.New("Store Down")
+}
+
+func (t *DownStore) Set(s sessions.Session) error {
+	return errors.New("Store Down")
+}
+
+func (t *DownStore) Delete(id string) error {
+	return errors.New("Store Down")
+}
+
+func (t *DownStore) All() ([]sessions.Session, error) {
+	return nil, errors.New("Store Down")
+}
+
+func TestReplicatedStore(t *testing.T) {
+	primary := NewMapStore()
+	secondary := NewMapStore()
+
+	events := make([]sessions.ReplicationEvent, 0)
+	rs := sessions.NewReplicatedStore(sessions.SyncReplication, 0, primary, secondary, &DownStore{})
+	rs.OnReplication = func(e sessions.ReplicationEvent) {
+		events = append(events, e)
+	}
+
+	s, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	s.Set("Key", "Value")
+	id, _ := s.ID()
+
+	err = rs.Set(s)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	if _, ok := secondary.Records[id]; !ok {
+		t.Fatalf("Error: session not replicated\n")
+	}
+
+	if len(events) != 2 || events[0].Err != nil || events[1].Err == nil {
+		t.Fatalf("Error: unexpected replication events %+v\n", events)
+	}
+
+	// Fail over to the secondary.
+	rs = sessions.NewReplicatedStore(sessions.SyncReplication, 0, &DownStore{}, secondary)
+	failover, err := rs.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session from secondary:%s\n", err.Error())
+	}
+
+	if v, _ := failover.Get("Key"); v != "Value" {
+		t.Fatalf("Error: expected \"Value\" received \"%v\"\n", v)
+	}
+
+	all, err := rs.All()
+	if err != nil || len(all) != 1 {
+		t.Fatalf("Error: expected 1 session from secondary got %d (%v)\n", len(all), err)
+	}
+}
+
+func TestReplicatedStoreAsync(t *testing.T) {
+	primary := NewMapStore()
+	secondary := NewMapStore()
+
+	rs := sessions.NewReplicatedStore(sessions.AsyncReplication, 10, primary, secondary)
+
+	s, _ := sessions.NewDefaultSession()
+	id, _ := s.ID()
+
+	err := rs.Set(s)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	err = rs.Delete(id)
+	if err != nil {
+		t.Fatalf("Error: deleting session:%s\n", err.Error())
+	}
+
+	s, _ = sessions.NewDefaultSession()
+	err = rs.Set(s)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	rs.Close()
+
+	if len(secondary.Records) != 1 || len(primary.Records) != 1 {
+		t.Fatalf("Error: secondary out of sync, primary:%d secondary:%d\n", len(primary.Records), len(secondary.Records))
+	}
+
+	// Writes after Close are refused rather than panicking.
+	if err := rs.Set(s); err != sessions.ErrStoreClosed {
+		t.Fatalf("Error: expected store closed error got %v\n", err)
+	}
+
+	if err := rs.Delete(id); err != sessions.ErrStoreClosed {
+		t.Fatalf("Error: expected store closed error got %v\n", err)
+	}
+
+	if err := rs.Close(); err != nil {
+		t.Fatalf("Error: closing twice:%s\n", err.Error())
+	}
+}
+
+func TestReplicatedStoreConformance(t *testing.T) {
+	sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+		rs := sessions.NewReplicatedStore(sessions.AsyncReplication, 1000, NewMapStore(), NewMapStore())
+		t.Cleanup(func() {
+			rs.Close()
+		})
+		return rs
+	})
+}
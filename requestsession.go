@@ -7,6 +7,9 @@ import (
 type RequestSession struct {
 	Request *http.Request
 	Session Session
+
+	// The session wasn't in the store when the request started.
+	New bool
 }
 
 type RequestSessions struct {
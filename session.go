@@ -55,3 +55,17 @@ type SessionStore interface {
 	// All, list all sessions in the store.
 	All() ([]Session, error)
 }
+
+// ExpiredDeleter is implemented by stores that can remove expired sessions without loading every session.
+type ExpiredDeleter interface {
+	// Delete every session that expired before the given time, returns the IDs of the deleted sessions.
+	DeleteExpired(before time.Time) ([]string, error)
+}
+
+// BatchStore is implemented by stores that can save several sessions at once (i.e. in a single transaction).
+type BatchStore interface {
+	SessionStore
+
+	// Save all the sessions to the store, either all or none should be saved.
+	SetAll(s []Session) error
+}
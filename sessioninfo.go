@@ -1,7 +1,10 @@
 package sessions
 
 import (
-	"log"
+	"bufio"
+	"errors"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
 )
@@ -15,21 +18,76 @@ type SessionInfo struct {
 	Timeout time.Duration
 	Store   SessionStore
 	Cache   RequestSessions
+
+	// The number of active sessions a user can have at once, 0 is unlimited.
+	MaxUserSessions int
+
+	// What Login does when the user already has MaxUserSessions active sessions.
+	SessionLimitPolicy SessionLimitPolicy
+
+	Hooks Hooks
+
+	// Where messages are logged, defaults to slog.Default().
+	Logger *slog.Logger
+
+	// The time sessions expire against, defaults to SystemClock.
+	Clock Clock
+
+	// Creates the IDs of new sessions, nil leaves it to the store.
+	// Incoming IDs the generator doesn't recognise are treated as no session at all.
+	IDGenerator IDGenerator
+
+	// Checks on the IDs sent by clients, IDs that fail are treated as no session at all.
+	IDValidation IDValidation
+
+	// How the session ID is sent between client and server, nil uses a cookie set up from Cookie.
+	Transport IDTransport
 }
 
 // Get the Session Id From the current Request.
+// IDs that fail validation are rejected (firing OnRejectID) and "" is returned.
 func (t *SessionInfo) GetSessionID(request *http.Request) (string, error) {
 
-	cookie, err := request.Cookie(t.Cookie.Name)
+	value, err := t.transport().ReadID(request)
+	if err != nil || value == "" {
+		return "", err
+	}
+
+	id, err := t.IDValidation.Verify(value)
+	if err == nil && t.IDGenerator != nil && !t.IDGenerator.Valid(id) {
+		err = ErrIDFormat
+	}
+
 	if err != nil {
-		if err == http.ErrNoCookie {
-			return "", nil
-		} else {
-			return "", err
-		}
+		t.logger().Debug("rejecting session id", "error", err)
+		t.Hooks.reject(value, err, request)
+		return "", nil
 	}
 
-	return cookie.Value, nil
+	return id, nil
+}
+
+func (t *SessionInfo) transport() IDTransport {
+	if t.Transport != nil {
+		return t.Transport
+	}
+	return t.cookieTransport()
+}
+
+func (t *SessionInfo) cookieTransport() CookieTransport {
+	return CookieTransport{
+		Name:   t.Cookie.Name,
+		Path:   t.Cookie.Path,
+		Domain: t.Cookie.Domain,
+		Clock:  t.Clock,
+	}
+}
+
+func (t *SessionInfo) logger() *slog.Logger {
+	if t.Logger != nil {
+		return t.Logger
+	}
+	return slog.Default()
 }
 
 // Get The session, try from cache then fallback to store.
@@ -42,73 +100,84 @@ func (t *SessionInfo) GetSession(request *http.Request) (Session, error) {
 
 	sessionid, err := t.GetSessionID(request)
 	if err != nil {
-		log.Printf("Debug: Error Getting Session ID For Request: %s\n", err.Error())
+		t.logger().Debug("getting session id for request", "error", err)
 		return nil, err
 	}
 
 	session, err := t.Store.Get(sessionid)
 	if err != nil {
-		log.Printf("Debug: Error Session For Session ID \"%s\" because: %s\n", sessionid, err.Error())
+		t.logger().Error("loading session", "session_id", sessionid, "error", err)
+		return nil, err
+	}
+
+	//Stores return a new session when the ID isn't found.
+	id, err := session.ID()
+	if err != nil {
 		return nil, err
 	}
 
+	if id != sessionid && t.IDGenerator != nil {
+		session, err = NewSessionWithGenerator(t.IDGenerator)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err = session.ID()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	t.Cache.Set(RequestSession{
 		Request: request,
 		Session: session,
+		New:     id != sessionid,
 	})
 
+	if id == sessionid {
+		t.Hooks.fire(t.Hooks.OnLoad, id, session, request)
+	}
+
 	return session, nil
 }
 
 // Try and Set the session id in the browsers cookie.
 func (t *SessionInfo) SetSessionCookie(response http.ResponseWriter, s Session) error {
-	//Cookies.....
-	cookie := &http.Cookie{
-		Name: t.Cookie.Name,
+	value, err := t.idValue(s)
+	if err != nil {
+		return err
 	}
 
-	if t.Cookie.Path != "" {
-		cookie.Path = t.Cookie.Path
-	} else {
-		cookie.Path = "/"
-	}
+	return t.cookieTransport().WriteID(response, nil, value, s.Expiry())
+}
 
-	if t.Cookie.Domain != "" {
-		cookie.Domain = t.Cookie.Domain
+// Send the session id back to the client with the Transport.
+func (t *SessionInfo) WriteSessionID(response http.ResponseWriter, request *http.Request, s Session) error {
+	value, err := t.idValue(s)
+	if err != nil {
+		return err
 	}
 
+	return t.transport().WriteID(response, request, value, s.Expiry())
+}
+
+// The value to send the client, empty when the client should forget the session.
+func (t *SessionInfo) idValue(s Session) (string, error) {
 	sessionkeys, err := s.Keys()
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	//If The Session Is Empty
-	if len(sessionkeys) <= 0 || s.Expiry().Before(time.Now()) {
-		//Expire the Cookie.
-		cookie.Value = ""
-		cookie.Expires = time.Now()
-		cookie.MaxAge = -1
-
-	} else {
-
-		//Expire the Cookie.
-		cookie.Value, err = s.ID()
-		if err != nil {
-			return err
-		}
-		cookie.Expires = s.Expiry()
-
-		if int64(^uint(0)>>1) < int64(s.Expiry().Sub(time.Now()).Seconds()) {
-			cookie.MaxAge = int(^uint(0) >> 1)
-		} else {
-			cookie.MaxAge = int(s.Expiry().Sub(time.Now()).Seconds())
-		}
-
+	if len(sessionkeys) <= 0 || s.Expiry().Before(now(t.Clock)) {
+		return "", nil
 	}
 
-	//Send the cookie back
-	http.SetCookie(response, cookie)
-	return nil
+	id, err := s.ID()
+	if err != nil {
+		return "", err
+	}
+	return t.IDValidation.Sign(id), nil
 }
 
 // Set Session in the Request Cache.
@@ -130,6 +199,36 @@ func (t *SessionInfo) PersistSession(request *http.Request) error {
 	return t.Store.Set(session)
 }
 
+// Destroy the request's session (i.e. logout), it's removed from the store and the client's cookie is expired.
+func (t *SessionInfo) Destroy(response http.ResponseWriter, request *http.Request) error {
+	session, err := t.GetSession(request)
+	if err != nil {
+		return err
+	}
+
+	id, err := session.ID()
+	if err != nil {
+		return err
+	}
+
+	// OnDestroy gets the session as it was.
+	destroyed, err := copySession(session)
+	if err != nil {
+		return err
+	}
+
+	//Empty the session so SaveSession doesn't write it back.
+	if err := session.Purge(); err != nil {
+		return err
+	}
+
+	if err := t.Hooks.destroy(t.Store, id, destroyed, request); err != nil {
+		return err
+	}
+
+	return t.WriteSessionID(response, request, session)
+}
+
 // Clear the Request From the Active Cache. This should always be done when getsession has been called.
 func (t *SessionInfo) ClearCache(request *http.Request) {
 	t.Cache.Delete(request)
@@ -146,40 +245,69 @@ func (t *SessionInfo) GetHandler(c http.Handler) http.Handler {
 }
 
 func (t *SessionInfo) SaveSession(w http.ResponseWriter, r *http.Request) {
+	if _, err := t.saveSession(w, r); err != nil {
+		http.Error(w, err.Error(), 500)
+	}
+}
+
+// Save the request's session and send its ID, stored is false when there was nothing to save.
+func (t *SessionInfo) saveSession(w http.ResponseWriter, r *http.Request) (stored bool, err error) {
 	//Did we use a session.
 	cache := t.Cache.Get(r)
-	if cache.Request != nil {
-		//Yep we did.
+	if cache.Request == nil {
+		return false, nil
+	}
 
-		//Do we have anything in the session?
-		keys, err := cache.Session.Keys()
-		if err != nil {
-			http.Error(w, err.Error(), 500)
-			return
-		}
+	//Do we have anything in the session?
+	keys, err := cache.Session.Keys()
+	if err != nil || len(keys) == 0 {
+		return false, err
+	}
 
-		if len(keys) > 0 {
-			//Increase the session expiry.
-			cache.Session.SetExpiry(time.Now().Add(t.Timeout))
-
-			//Save the updated session to cache.
-			t.SetSession(r, cache.Session)
-
-			//Store the session to disk.
-			err := t.PersistSession(r)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				return
-			}
-
-			//Save the session
-			//This is needed when start the session for the first time.
-			err = t.SetSessionCookie(w, cache.Session)
-			if err != nil {
-				http.Error(w, err.Error(), 500)
-				return
-			}
-		}
+	//Increase the session expiry.
+	cache.Session.SetExpiry(now(t.Clock).Add(t.Timeout))
+
+	//Save the updated session to cache.
+	t.SetSession(r, cache.Session)
+
+	//Store the session to disk.
+	if err := t.PersistSession(r); err != nil {
+		return false, err
+	}
+
+	//Save the session
+	//This is needed when start the session for the first time.
+	if err := t.WriteSessionID(w, r, cache.Session); err != nil {
+		return true, err
+	}
+
+	id, err := cache.Session.ID()
+	if err != nil {
+		return true, err
+	}
+
+	if cache.New {
+		t.Hooks.fire(t.Hooks.OnCreate, id, cache.Session, r)
+	}
+	t.Hooks.fire(t.Hooks.OnSave, id, cache.Session, r)
+	return true, nil
+}
+
+// Store changes made to a session after the response was started, the ID has already been sent.
+func (t *SessionInfo) persistLate(r *http.Request) {
+	cache := t.Cache.Get(r)
+	if cache.Request == nil {
+		return
+	}
+
+	//Destroyed since, don't write it back.
+	keys, err := cache.Session.Keys()
+	if err != nil || len(keys) == 0 {
+		return
+	}
+
+	if err := t.Store.Set(cache.Session); err != nil {
+		t.logger().Error("saving session after the response was started", "error", err)
 	}
 }
 
@@ -194,19 +322,96 @@ func (t sessionInfoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	session, err := t.GetSession(r)
 	if err == nil {
 		//Tell the client to update it's cookie.
-		err = t.SetSessionCookie(w, session)
+		err = t.WriteSessionID(w, r, session)
 		if err != nil {
 			http.Error(w, err.Error(), 500)
 			return
 		}
 	}
 
-	//Call the inner servehttp.
-	t.Handler.ServeHTTP(w, r)
+	//Call the inner servehttp, the session is saved as the response is started.
+	sw := &sessionWriter{
+		ResponseWriter: w,
+		info:           t.SessionInfo,
+		request:        r,
+	}
+	t.Handler.ServeHTTP(sw, r)
 
-	//Save the session
-	t.SessionInfo.SaveSession(w, r)
+	if !sw.started {
+		//Nothing written, save the session before the server sends the headers.
+		sw.save()
+	} else if sw.stored {
+		t.SessionInfo.persistLate(r)
+	}
 
 	//Always clear our cache to free up resource.
 	t.SessionInfo.ClearCache(r)
 }
+
+// sessionWriter saves the session and sends its ID before the first WriteHeader or Write,
+// after that the headers (and so the ID) can't be changed.
+type sessionWriter struct {
+	http.ResponseWriter
+	info    *SessionInfo
+	request *http.Request
+
+	// The response has been started.
+	started bool
+
+	// The session was saved when it was started.
+	stored bool
+
+	// Saving failed and a 500 was sent in place of the handler's response.
+	failed bool
+}
+
+func (t *sessionWriter) save() {
+	if t.started {
+		return
+	}
+	t.started = true
+
+	stored, err := t.info.saveSession(t.ResponseWriter, t.request)
+	t.stored = stored
+	if err != nil {
+		t.failed = true
+		http.Error(t.ResponseWriter, err.Error(), 500)
+	}
+}
+
+func (t *sessionWriter) WriteHeader(code int) {
+	t.save()
+	if !t.failed {
+		t.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (t *sessionWriter) Write(b []byte) (int, error) {
+	t.save()
+	if t.failed {
+		return len(b), nil
+	}
+	return t.ResponseWriter.Write(b)
+}
+
+func (t *sessionWriter) Flush() {
+	t.save()
+	if f, ok := t.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// The session is saved first, the connection is the caller's after this.
+func (t *sessionWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	t.save()
+	h, ok := t.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("sessions: response writer doesn't support hijacking")
+	}
+	return h.Hijack()
+}
+
+// For http.ResponseController.
+func (t *sessionWriter) Unwrap() http.ResponseWriter {
+	return t.ResponseWriter
+}
@@ -1,9 +1,13 @@
 package sessions_test
 
 import (
+	"bytes"
 	"errors"
 	"github.com/d2g/sessions"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -43,6 +47,76 @@ func (t *MockStore) All() ([]sessions.Session, error) {
 	return []sessions.Session{}, nil
 }
 
+// MapStore is an in memory store that, like the real stores, only keeps the encoded session.
+type MapStore struct {
+	sync.Mutex
+	Records map[string][]byte
+}
+
+func NewMapStore() *MapStore {
+	return &MapStore{Records: make(map[string][]byte)}
+}
+
+func (t *MapStore) Get(id string) (sessions.Session, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	s, err := sessions.NewDefaultSession()
+	if err != nil {
+		return nil, err
+	}
+
+	if b, ok := t.Records[id]; ok {
+		err = s.GobDecode(b)
+	}
+	return s, err
+}
+
+func (t *MapStore) Set(s sessions.Session) error {
+	t.Lock()
+	defer t.Unlock()
+
+	id, err := s.ID()
+	if err != nil {
+		return err
+	}
+
+	b, err := s.GobEncode()
+	if err != nil {
+		return err
+	}
+
+	t.Records[id] = b
+	return nil
+}
+
+func (t *MapStore) Delete(id string) error {
+	t.Lock()
+	defer t.Unlock()
+
+	delete(t.Records, id)
+	return nil
+}
+
+func (t *MapStore) All() ([]sessions.Session, error) {
+	t.Lock()
+	defer t.Unlock()
+
+	all := make([]sessions.Session, 0, len(t.Records))
+	for _, b := range t.Records {
+		s, err := sessions.NewDefaultSession()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.GobDecode(b); err != nil {
+			return nil, err
+		}
+		all = append(all, s)
+	}
+	return all, nil
+}
+
 func TestSessionInfo(t *testing.T) {
 	si := sessions.SessionInfo{}
 	si.Cookie.Name = "SESSIONID"
@@ -131,3 +205,27 @@ func TestSessionInfo(t *testing.T) {
 	}
 
 }
+
+func TestSessionInfoLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+
+	si := sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Store = &MockStore{}
+	si.Logger = slog.New(slog.NewTextHandler(buf, nil))
+
+	r, err := http.NewRequest("GET", "http://example.com", nil)
+	if err != nil {
+		t.Fatalf("Error: creating dummy request:%s\n", err.Error())
+	}
+	r.AddCookie(&http.Cookie{Name: si.Cookie.Name, Value: "ERROR"})
+
+	_, err = si.GetSession(r)
+	if err == nil {
+		t.Fatalf("Error: expected error getting session\n")
+	}
+
+	if !strings.Contains(buf.String(), "level=ERROR") || !strings.Contains(buf.String(), "session_id=ERROR") {
+		t.Fatalf("Error: unexpected log output %s\n", buf.String())
+	}
+}
@@ -0,0 +1,37 @@
+package sessionstest
+
+import (
+	"sync"
+	"time"
+)
+
+// FakeClock is a sessions.Clock that only moves when told to, so expiry can be tested without sleeping.
+type FakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// Create a FakeClock stopped at now.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+func (t *FakeClock) Now() time.Time {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.now
+}
+
+// Move the clock on by d.
+func (t *FakeClock) Advance(d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = t.now.Add(d)
+}
+
+// Stop the clock at now.
+func (t *FakeClock) Set(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.now = now
+}
@@ -0,0 +1,26 @@
+package sessionstest_test
+
+import (
+	"github.com/d2g/sessions/sessionstest"
+	"testing"
+	"time"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := sessionstest.NewFakeClock(start)
+
+	if !c.Now().Equal(start) {
+		t.Fatalf("Error: expected %v got %v\n", start, c.Now())
+	}
+
+	c.Advance(time.Hour)
+	if !c.Now().Equal(start.Add(time.Hour)) {
+		t.Fatalf("Error: expected %v got %v\n", start.Add(time.Hour), c.Now())
+	}
+
+	c.Set(start)
+	if !c.Now().Equal(start) {
+		t.Fatalf("Error: expected %v got %v\n", start, c.Now())
+	}
+}
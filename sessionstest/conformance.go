@@ -0,0 +1,330 @@
+// Package sessionstest provides helpers for testing session stores and session aware handlers.
+package sessionstest
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/d2g/sessions"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Check the store behaves as the sessions package expects of a SessionStore.
+// newStore is called for each check and must return a new empty store.
+func RunStoreConformance(t *testing.T, newStore func() sessions.SessionStore) {
+	checks := []struct {
+		name  string
+		check func(*testing.T, sessions.SessionStore)
+	}{
+		{"GetEmptyID", testGetEmptyID},
+		{"GetMissing", testGetMissing},
+		{"RoundTrip", testRoundTrip},
+		{"Overwrite", testOverwrite},
+		{"Delete", testDelete},
+		{"DeleteMissing", testDeleteMissing},
+		{"AllEmpty", testAllEmpty},
+		{"All", testAll},
+		{"Concurrent", testConcurrent},
+		{"LargePayload", testLargePayload},
+	}
+
+	for _, c := range checks {
+		check := c.check
+		t.Run(c.name, func(t *testing.T) {
+			check(t, newStore())
+		})
+	}
+}
+
+func newSession(t *testing.T, values map[interface{}]interface{}) (sessions.Session, string) {
+	return newGeneratedSession(t, nil, values)
+}
+
+// As newSession, with the ID from generator (the default IDs when it's nil).
+func newGeneratedSession(t *testing.T, generator sessions.IDGenerator, values map[interface{}]interface{}) (sessions.Session, string) {
+	var s sessions.Session
+	var err error
+
+	if generator != nil {
+		s, err = sessions.NewSessionWithGenerator(generator)
+	} else {
+		s, err = sessions.NewDefaultSession()
+	}
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+
+	for k, v := range values {
+		if err := s.Set(k, v); err != nil {
+			t.Fatalf("Error: setting value to session:%s\n", err.Error())
+		}
+	}
+
+	id, err := s.ID()
+	if err != nil {
+		t.Fatalf("Error: getting session id:%s\n", err.Error())
+	}
+
+	return s, id
+}
+
+// Get the session and check it's the one that was saved.
+func get(t *testing.T, store sessions.SessionStore, id string) sessions.Session {
+	s, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session %s:%s\n", id, err.Error())
+	}
+
+	got, err := s.ID()
+	if err != nil {
+		t.Fatalf("Error: getting session id:%s\n", err.Error())
+	}
+
+	if got != id {
+		t.Fatalf("Error: expected session %s got %s\n", id, got)
+	}
+	return s
+}
+
+func checkNew(t *testing.T, s sessions.Session) {
+	id, err := s.ID()
+	if err != nil {
+		t.Fatalf("Error: getting session id:%s\n", err.Error())
+	}
+
+	if id == "" {
+		t.Fatalf("Error: new session has no id\n")
+	}
+
+	keys, err := s.Keys()
+	if err != nil {
+		t.Fatalf("Error: getting keys from session:%s\n", err.Error())
+	}
+
+	if len(keys) != 0 {
+		t.Fatalf("Error: expected new session to be empty got %d keys\n", len(keys))
+	}
+
+	// New sessions must be usable.
+	if err := s.Set("Key", "Value"); err != nil {
+		t.Fatalf("Error: setting value to new session:%s\n", err.Error())
+	}
+}
+
+func testGetEmptyID(t *testing.T, store sessions.SessionStore) {
+	s, err := store.Get("")
+	if err != nil {
+		t.Fatalf("Error: getting session for empty id:%s\n", err.Error())
+	}
+
+	checkNew(t, s)
+}
+
+func testGetMissing(t *testing.T, store sessions.SessionStore) {
+	s, err := store.Get("MISSING")
+	if err != nil {
+		t.Fatalf("Error: getting missing session:%s\n", err.Error())
+	}
+
+	// The store mustn't hand out a session with an ID the client chose.
+	if id, _ := s.ID(); id == "MISSING" {
+		t.Fatalf("Error: new session took the requested id\n")
+	}
+
+	checkNew(t, s)
+}
+
+func testRoundTrip(t *testing.T, store sessions.SessionStore) {
+	s, id := newSession(t, map[interface{}]interface{}{
+		"String": "Value",
+		"Int":    42,
+		"Bytes":  []byte{1, 2, 3},
+	})
+
+	expiry := time.Now().Add(time.Hour)
+	s.SetExpiry(expiry)
+
+	if err := store.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	s = get(t, store, id)
+
+	if !s.Expiry().Equal(expiry) {
+		t.Fatalf("Error: expiry got %v wanted %v\n", s.Expiry(), expiry)
+	}
+
+	if v, _ := s.Get("String"); v != "Value" {
+		t.Fatalf("Error: expected \"Value\" received \"%v\"\n", v)
+	}
+
+	if v, _ := s.Get("Int"); v != 42 {
+		t.Fatalf("Error: expected 42 received %v\n", v)
+	}
+
+	if v, _ := s.Get("Bytes"); !bytes.Equal(v.([]byte), []byte{1, 2, 3}) {
+		t.Fatalf("Error: expected [1 2 3] received %v\n", v)
+	}
+}
+
+func testOverwrite(t *testing.T, store sessions.SessionStore) {
+	s, id := newSession(t, map[interface{}]interface{}{"Key": "First", "Removed": true})
+
+	if err := store.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	s.Set("Key", "Second")
+	s.Delete("Removed")
+
+	if err := store.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	s = get(t, store, id)
+
+	if v, _ := s.Get("Key"); v != "Second" {
+		t.Fatalf("Error: expected \"Second\" received \"%v\"\n", v)
+	}
+
+	if keys, _ := s.Keys(); len(keys) != 1 {
+		t.Fatalf("Error: expected 1 key got %d\n", len(keys))
+	}
+}
+
+func testDelete(t *testing.T, store sessions.SessionStore) {
+	s, id := newSession(t, map[interface{}]interface{}{"Key": "Value"})
+
+	if err := store.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	if err := store.Delete(id); err != nil {
+		t.Fatalf("Error: deleting session:%s\n", err.Error())
+	}
+
+	s, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting deleted session:%s\n", err.Error())
+	}
+
+	if got, _ := s.ID(); got == id {
+		t.Fatalf("Error: deleted session still in the store\n")
+	}
+}
+
+func testDeleteMissing(t *testing.T, store sessions.SessionStore) {
+	if err := store.Delete("MISSING"); err != nil {
+		t.Fatalf("Error: deleting missing session:%s\n", err.Error())
+	}
+}
+
+func testAllEmpty(t *testing.T, store sessions.SessionStore) {
+	all, err := store.All()
+	if err != nil {
+		t.Fatalf("Error: getting all sessions from empty store:%s\n", err.Error())
+	}
+
+	if len(all) != 0 {
+		t.Fatalf("Error: expected 0 sessions got %d\n", len(all))
+	}
+}
+
+func testAll(t *testing.T, store sessions.SessionStore) {
+	ids := make(map[string]bool)
+	for i := 0; i < 10; i++ {
+		s, id := newSession(t, map[interface{}]interface{}{"Key": i})
+		if err := store.Set(s); err != nil {
+			t.Fatalf("Error: saving session:%s\n", err.Error())
+		}
+		ids[id] = true
+	}
+
+	all, err := store.All()
+	if err != nil {
+		t.Fatalf("Error: getting all sessions:%s\n", err.Error())
+	}
+
+	if len(all) != len(ids) {
+		t.Fatalf("Error: expected %d sessions got %d\n", len(ids), len(all))
+	}
+
+	for _, s := range all {
+		id, _ := s.ID()
+		if !ids[id] {
+			t.Fatalf("Error: unexpected session %s\n", id)
+		}
+		delete(ids, id)
+	}
+}
+
+func testConcurrent(t *testing.T, store sessions.SessionStore) {
+	var wg sync.WaitGroup
+	errs := make(chan error, 100)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < 10; j++ {
+				s, err := sessions.NewDefaultSession()
+				if err != nil {
+					errs <- err
+					return
+				}
+				s.Set("Key", fmt.Sprintf("%d-%d", i, j))
+
+				if err := store.Set(s); err != nil {
+					errs <- err
+					return
+				}
+
+				id, _ := s.ID()
+				got, err := store.Get(id)
+				if err != nil {
+					errs <- err
+					return
+				}
+
+				if v, _ := got.Get("Key"); v != fmt.Sprintf("%d-%d", i, j) {
+					errs <- fmt.Errorf("session %s has value %v", id, v)
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Fatalf("Error: concurrent use:%s\n", err.Error())
+	}
+
+	all, err := store.All()
+	if err != nil {
+		t.Fatalf("Error: getting all sessions:%s\n", err.Error())
+	}
+
+	if len(all) != 100 {
+		t.Fatalf("Error: expected 100 sessions got %d\n", len(all))
+	}
+}
+
+func testLargePayload(t *testing.T, store sessions.SessionStore) {
+	large := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	s, id := newSession(t, map[interface{}]interface{}{"Large": large})
+
+	if err := store.Set(s); err != nil {
+		t.Fatalf("Error: saving large session:%s\n", err.Error())
+	}
+
+	s = get(t, store, id)
+
+	v, _ := s.Get("Large")
+	if b, ok := v.([]byte); !ok || !bytes.Equal(b, large) {
+		t.Fatalf("Error: large value not returned intact\n")
+	}
+}
@@ -0,0 +1,181 @@
+package sessionstest
+
+import (
+	"github.com/d2g/sessions"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Create a request carrying the ID of a session holding values, sent the way info.Transport reads it (the cookie when it's not set).
+// The session is saved to info.Store, expiring info.Timeout (or an hour when it's not set) from info.Clock's now.
+// Its ID comes from info.IDGenerator when one is set.
+func NewRequest(t *testing.T, info *sessions.SessionInfo, method, target string, body io.Reader, values map[interface{}]interface{}) *http.Request {
+	s, id := newGeneratedSession(t, info.IDGenerator, values)
+
+	timeout := info.Timeout
+	if timeout <= 0 {
+		timeout = time.Hour
+	}
+	clock := info.Clock
+	if clock == nil {
+		clock = sessions.SystemClock{}
+	}
+	s.SetExpiry(clock.Now().Add(timeout))
+
+	if err := info.Store.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	r := httptest.NewRequest(method, target, body)
+	addID(t, r, info.Transport, info.Cookie.Name, info.IDValidation.Sign(id))
+	return r
+}
+
+// Put the ID on the request where transport reads it from.
+func addID(t *testing.T, r *http.Request, transport sessions.IDTransport, cookie string, value string) {
+	switch tr := transport.(type) {
+	case nil:
+		r.AddCookie(&http.Cookie{Name: cookie, Value: value})
+	case sessions.CookieTransport:
+		r.AddCookie(&http.Cookie{Name: tr.Name, Value: value})
+	case sessions.HeaderTransport:
+		r.Header.Set(headerName(tr.Name), value)
+	case sessions.BearerTransport:
+		r.Header.Set("Authorization", "Bearer "+value)
+	case sessions.QueryTransport:
+		q := r.URL.Query()
+		q.Set(tr.Parameter, value)
+		r.URL.RawQuery = q.Encode()
+	case sessions.TransportChain:
+		if len(tr) == 0 {
+			t.Fatalf("Error: empty transport chain\n")
+		}
+		addID(t, r, tr[0], cookie, value)
+	default:
+		t.Fatalf("Error: unsupported transport %T\n", transport)
+	}
+}
+
+// The response header the transports default to.
+func headerName(name string) string {
+	if name == "" {
+		return "X-Session-Token"
+	}
+	return name
+}
+
+// Recorder is an httptest.ResponseRecorder that knows where to find the session the handler left behind.
+type Recorder struct {
+	*httptest.ResponseRecorder
+	info *sessions.SessionInfo
+}
+
+func NewRecorder(info *sessions.SessionInfo) *Recorder {
+	return &Recorder{
+		ResponseRecorder: httptest.NewRecorder(),
+		info:             info,
+	}
+}
+
+// The last session cookie written, nil if the handler didn't write one.
+// SessionInfo's handler can write the cookie twice, the last one is what the client keeps.
+func (t *Recorder) Cookie() *http.Cookie {
+	name := t.info.Cookie.Name
+	if tr, ok := t.info.Transport.(sessions.CookieTransport); ok {
+		name = tr.Name
+	}
+	return t.cookie(name)
+}
+
+func (t *Recorder) cookie(name string) *http.Cookie {
+	var cookie *http.Cookie
+	for _, c := range t.Result().Cookies() {
+		if c.Name == name {
+			cookie = c
+		}
+	}
+	return cookie
+}
+
+// The ID the client was left with, read the way info.Transport writes it (the cookie when it's not set).
+// "" when the handler didn't send one or told the client to forget it.
+func (t *Recorder) ID() string {
+	value, _ := t.id(t.info.Transport)
+	return value
+}
+
+// ok is false when transport didn't write anything.
+func (t *Recorder) id(transport sessions.IDTransport) (string, bool) {
+	switch tr := transport.(type) {
+	case nil:
+		return cookieID(t.cookie(t.info.Cookie.Name))
+	case sessions.CookieTransport:
+		return cookieID(t.cookie(tr.Name))
+	case sessions.HeaderTransport:
+		return t.header(headerName(tr.Name))
+	case sessions.BearerTransport:
+		return t.header(headerName(tr.Header))
+	case sessions.QueryTransport:
+		return t.header(headerName(tr.Header))
+	case sessions.TransportChain:
+		//Written with the transport the request used, or all of them.
+		for _, transport := range tr {
+			if value, ok := t.id(transport); ok {
+				return value, true
+			}
+		}
+	}
+	return "", false
+}
+
+func (t *Recorder) header(name string) (string, bool) {
+	values, ok := t.Result().Header[http.CanonicalHeaderKey(name)]
+	if !ok || len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+func cookieID(cookie *http.Cookie) (string, bool) {
+	if cookie == nil {
+		return "", false
+	}
+
+	if cookie.MaxAge < 0 {
+		return "", true
+	}
+	return cookie.Value, true
+}
+
+// The session the client was left with, loaded from info.Store.
+// nil when there's no ID, the client was told to forget it or the session isn't in the store.
+func (t *Recorder) Session(tt *testing.T) sessions.Session {
+	value := t.ID()
+	if value == "" {
+		return nil
+	}
+
+	want, err := t.info.IDValidation.Verify(value)
+	if err != nil {
+		tt.Fatalf("Error: verifying session cookie:%s\n", err.Error())
+	}
+
+	s, err := t.info.Store.Get(want)
+	if err != nil {
+		tt.Fatalf("Error: loading session:%s\n", err.Error())
+	}
+
+	id, err := s.ID()
+	if err != nil {
+		tt.Fatalf("Error: getting session id:%s\n", err.Error())
+	}
+
+	//Stores return a new session when the ID isn't found.
+	if id != want {
+		return nil
+	}
+	return s
+}
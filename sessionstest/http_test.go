@@ -0,0 +1,199 @@
+package sessionstest_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRequestRecorder(t *testing.T) {
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Timeout = time.Hour
+	si.Store = sessionstest.NewMemoryStore()
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := si.GetSession(r)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		v, err := s.Get("User")
+		if err != nil {
+			t.Fatalf("Error: getting value from session:%s\n", err.Error())
+		}
+
+		if v != "alice" {
+			t.Fatalf("Error: expected alice got %v\n", v)
+		}
+
+		s.Set("Visits", 1)
+	}))
+
+	r := sessionstest.NewRequest(t, si, "GET", "/", nil, map[interface{}]interface{}{"User": "alice"})
+	w := sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, r)
+
+	cookie := w.Cookie()
+	if cookie == nil {
+		t.Fatalf("Error: session cookie not set\n")
+	}
+
+	id, err := si.GetSessionID(r)
+	if err != nil {
+		t.Fatalf("Error: getting session id:%s\n", err.Error())
+	}
+
+	if cookie.Value != id {
+		t.Fatalf("Error: expected cookie %s got %s\n", id, cookie.Value)
+	}
+
+	s := w.Session(t)
+	if s == nil {
+		t.Fatalf("Error: session not saved\n")
+	}
+
+	v, err := s.Get("Visits")
+	if err != nil {
+		t.Fatalf("Error: getting value from session:%s\n", err.Error())
+	}
+
+	if v != 1 {
+		t.Fatalf("Error: expected 1 got %v\n", v)
+	}
+}
+
+func TestRecorderDestroyed(t *testing.T) {
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Store = sessionstest.NewMemoryStore()
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := si.Destroy(w, r); err != nil {
+			t.Fatalf("Error: destroying session:%s\n", err.Error())
+		}
+	}))
+
+	r := sessionstest.NewRequest(t, si, "POST", "/logout", nil, map[interface{}]interface{}{"User": "alice"})
+	w := sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, r)
+
+	if w.Cookie() == nil {
+		t.Fatalf("Error: session cookie not set\n")
+	}
+
+	if s := w.Session(t); s != nil {
+		t.Fatalf("Error: expected no session after destroy\n")
+	}
+}
+
+func TestRequestIDGenerator(t *testing.T) {
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Store = sessionstest.NewMemoryStore()
+	si.IDGenerator = sessions.UUIDv4Generator{}
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := si.GetSession(r)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		// The session is found rather than replaced with a new one for having a foreign ID.
+		if v, _ := s.Get("User"); v != "alice" {
+			t.Fatalf("Error: expected alice got %v\n", v)
+		}
+	}))
+
+	r := sessionstest.NewRequest(t, si, "GET", "/", nil, map[interface{}]interface{}{"User": "alice"})
+
+	id, err := si.GetSessionID(r)
+	if err != nil {
+		t.Fatalf("Error: getting session id:%s\n", err.Error())
+	}
+
+	if !si.IDGenerator.Valid(id) {
+		t.Fatalf("Error: expected a UUID got %s\n", id)
+	}
+
+	h.ServeHTTP(sessionstest.NewRecorder(si), r)
+}
+
+func TestRequestTransport(t *testing.T) {
+	transports := []sessions.IDTransport{
+		sessions.CookieTransport{Name: "OTHERSESSION"},
+		sessions.HeaderTransport{},
+		sessions.BearerTransport{Header: "X-Token"},
+		sessions.QueryTransport{Parameter: "session"},
+		sessions.TransportChain{sessions.HeaderTransport{Name: "X-Session"}, sessions.CookieTransport{Name: "SESSIONID"}},
+	}
+
+	for _, transport := range transports {
+		si := &sessions.SessionInfo{}
+		si.Cookie.Name = "SESSIONID"
+		si.Timeout = time.Hour
+		si.Store = sessionstest.NewMemoryStore()
+		si.Transport = transport
+
+		h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			s, err := si.GetSession(r)
+			if err != nil {
+				t.Fatalf("Error: getting session:%s\n", err.Error())
+			}
+
+			if v, _ := s.Get("User"); v != "alice" {
+				t.Fatalf("Error: expected alice with %T got %v\n", transport, v)
+			}
+
+			s.Set("Visits", 1)
+		}))
+
+		r := sessionstest.NewRequest(t, si, "GET", "/", nil, map[interface{}]interface{}{"User": "alice"})
+		w := sessionstest.NewRecorder(si)
+		h.ServeHTTP(w, r)
+
+		id, err := si.GetSessionID(r)
+		if err != nil {
+			t.Fatalf("Error: getting session id:%s\n", err.Error())
+		}
+
+		if w.ID() != id {
+			t.Fatalf("Error: expected %s with %T got %q\n", id, transport, w.ID())
+		}
+
+		s := w.Session(t)
+		if s == nil {
+			t.Fatalf("Error: session not found with %T\n", transport)
+		}
+
+		if v, _ := s.Get("Visits"); v != 1 {
+			t.Fatalf("Error: expected 1 got %v\n", v)
+		}
+	}
+}
+
+func TestRecorderDestroyedHeader(t *testing.T) {
+	si := &sessions.SessionInfo{}
+	si.Store = sessionstest.NewMemoryStore()
+	si.Transport = sessions.HeaderTransport{}
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := si.Destroy(w, r); err != nil {
+			t.Fatalf("Error: destroying session:%s\n", err.Error())
+		}
+	}))
+
+	r := sessionstest.NewRequest(t, si, "POST", "/logout", nil, map[interface{}]interface{}{"User": "alice"})
+	w := sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, r)
+
+	if w.ID() != "" {
+		t.Fatalf("Error: expected the ID cleared got %q\n", w.ID())
+	}
+
+	if s := w.Session(t); s != nil {
+		t.Fatalf("Error: expected no session after destroy\n")
+	}
+}
@@ -0,0 +1,76 @@
+package sessionstest
+
+import (
+	"github.com/d2g/sessions"
+	"sync"
+)
+
+// MemoryStore is an in memory SessionStore for tests.
+// Like the real stores it keeps the encoded session, so changes aren't visible until the session is saved.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{records: make(map[string][]byte)}
+}
+
+func (t *MemoryStore) Get(id string) (sessions.Session, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, err := sessions.NewDefaultSession()
+	if err != nil {
+		return nil, err
+	}
+
+	if b, ok := t.records[id]; ok {
+		err = s.GobDecode(b)
+	}
+	return s, err
+}
+
+func (t *MemoryStore) Set(s sessions.Session) error {
+	id, err := s.ID()
+	if err != nil {
+		return err
+	}
+
+	b, err := s.GobEncode()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.records[id] = b
+	return nil
+}
+
+func (t *MemoryStore) Delete(id string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.records, id)
+	return nil
+}
+
+func (t *MemoryStore) All() ([]sessions.Session, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	all := make([]sessions.Session, 0, len(t.records))
+	for _, b := range t.records {
+		s, err := sessions.NewDefaultSession()
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.GobDecode(b); err != nil {
+			return nil, err
+		}
+		all = append(all, s)
+	}
+	return all, nil
+}
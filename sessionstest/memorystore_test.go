@@ -0,0 +1,13 @@
+package sessionstest_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"testing"
+)
+
+func TestMemoryStoreConformance(t *testing.T) {
+	sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+		return sessionstest.NewMemoryStore()
+	})
+}
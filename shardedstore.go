@@ -0,0 +1,225 @@
+package sessions
+
+import (
+	"errors"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// ShardedStore spreads sessions over several stores by consistent hashing of the session ID.
+//
+// Each shard is placed on the hash ring replicas times so sessions are spread evenly,
+// adding a shard only moves the sessions that now belong to it.
+type ShardedStore struct {
+	replicas int
+
+	mu     sync.RWMutex
+	shards map[string]SessionStore
+	ring   []shardPoint
+}
+
+type shardPoint struct {
+	hash  uint32
+	shard string
+}
+
+// Create a ShardedStore over the named shards.
+// The names place the shards on the ring so they must stay the same between restarts.
+func NewShardedStore(replicas int, shards map[string]SessionStore) *ShardedStore {
+	if replicas <= 0 {
+		replicas = 1
+	}
+
+	t := &ShardedStore{
+		replicas: replicas,
+		shards:   make(map[string]SessionStore),
+	}
+
+	for name, store := range shards {
+		t.shards[name] = store
+	}
+	t.buildRing()
+
+	return t
+}
+
+func (t *ShardedStore) Get(id string) (Session, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	store, err := t.shard(id)
+	if err != nil {
+		return nil, err
+	}
+	return store.Get(id)
+}
+
+func (t *ShardedStore) Set(s Session) error {
+	id, err := s.ID()
+	if err != nil {
+		return err
+	}
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	store, err := t.shard(id)
+	if err != nil {
+		return err
+	}
+	return store.Set(s)
+}
+
+func (t *ShardedStore) Delete(id string) error {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	store, err := t.shard(id)
+	if err != nil {
+		return err
+	}
+	return store.Delete(id)
+}
+
+// All the sessions from every shard.
+func (t *ShardedStore) All() ([]Session, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	all := make([]Session, 0)
+	for _, store := range t.shards {
+		s, err := store.All()
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, s...)
+	}
+	return all, nil
+}
+
+// Add a shard and move the sessions that now belong to it, returns the number of sessions moved.
+// The store is locked while sessions are moved.
+// If moving fails the shard is taken off the ring and the sessions already moved are put back.
+func (t *ShardedStore) AddShard(name string, store SessionStore) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.shards[name]; ok {
+		return 0, errors.New("sessions: shard \"" + name + "\" already exists")
+	}
+
+	t.shards[name] = store
+	t.buildRing()
+
+	moved, err := t.rebalance()
+	if err != nil {
+		delete(t.shards, name)
+		t.buildRing()
+
+		return 0, errors.Join(err, t.restore(store, moved))
+	}
+
+	return len(moved), nil
+}
+
+// Move any sessions that are on the wrong shard, returns the sessions copied to their new shard.
+// Must be called with the lock held.
+func (t *ShardedStore) rebalance() ([]Session, error) {
+	moved := make([]Session, 0)
+
+	for name, store := range t.shards {
+		all, err := store.All()
+		if err != nil {
+			return moved, err
+		}
+
+		for _, s := range all {
+			id, err := s.ID()
+			if err != nil {
+				return moved, err
+			}
+
+			owner := t.owner(id)
+			if owner == name {
+				continue
+			}
+
+			if err := t.shards[owner].Set(s); err != nil {
+				return moved, err
+			}
+			moved = append(moved, s)
+
+			if err := store.Delete(id); err != nil {
+				return moved, err
+			}
+		}
+	}
+
+	return moved, nil
+}
+
+// Put sessions moved to a shard that's been taken off the ring back on the shards that own them.
+// Must be called with the lock held.
+func (t *ShardedStore) restore(from SessionStore, moved []Session) error {
+	for _, s := range moved {
+		id, err := s.ID()
+		if err != nil {
+			return err
+		}
+
+		if err := t.shards[t.owner(id)].Set(s); err != nil {
+			return err
+		}
+
+		if err := from.Delete(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Must be called with the lock held.
+func (t *ShardedStore) buildRing() {
+	t.ring = make([]shardPoint, 0, len(t.shards)*t.replicas)
+
+	for name := range t.shards {
+		for i := 0; i < t.replicas; i++ {
+			t.ring = append(t.ring, shardPoint{
+				hash:  crc32.ChecksumIEEE([]byte(name + "#" + strconv.Itoa(i))),
+				shard: name,
+			})
+		}
+	}
+
+	sort.Slice(t.ring, func(i, j int) bool {
+		if t.ring[i].hash == t.ring[j].hash {
+			return t.ring[i].shard < t.ring[j].shard
+		}
+		return t.ring[i].hash < t.ring[j].hash
+	})
+}
+
+// The name of the shard the session ID belongs to.
+// Must be called with the lock held.
+func (t *ShardedStore) owner(id string) string {
+	h := crc32.ChecksumIEEE([]byte(id))
+
+	i := sort.Search(len(t.ring), func(i int) bool {
+		return t.ring[i].hash >= h
+	})
+
+	if i == len(t.ring) {
+		i = 0
+	}
+	return t.ring[i].shard
+}
+
+// Must be called with the lock held.
+func (t *ShardedStore) shard(id string) (SessionStore, error) {
+	if len(t.ring) == 0 {
+		return nil, errors.New("sessions: no shards")
+	}
+	return t.shards[t.owner(id)], nil
+}
@@ -0,0 +1,149 @@
+package sessions_test
+
+import (
+	"errors"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"testing"
+)
+
+func TestShardedStore(t *testing.T) {
+	a := NewMapStore()
+	b := NewMapStore()
+
+	ss := sessions.NewShardedStore(16, map[string]sessions.SessionStore{
+		"a": a,
+		"b": b,
+	})
+
+	ids := make([]string, 0)
+	for i := 0; i < 100; i++ {
+		s, err := sessions.NewDefaultSession()
+		if err != nil {
+			t.Fatalf("Error: creating new session:%s\n", err.Error())
+		}
+		s.Set("Key", i)
+
+		err = ss.Set(s)
+		if err != nil {
+			t.Fatalf("Error: saving session:%s\n", err.Error())
+		}
+
+		id, _ := s.ID()
+		ids = append(ids, id)
+	}
+
+	if len(a.Records) == 0 || len(b.Records) == 0 || len(a.Records)+len(b.Records) != 100 {
+		t.Fatalf("Error: sessions not spread over shards a:%d b:%d\n", len(a.Records), len(b.Records))
+	}
+
+	c := NewMapStore()
+	moved, err := ss.AddShard("c", c)
+	if err != nil {
+		t.Fatalf("Error: adding shard:%s\n", err.Error())
+	}
+
+	if moved == 0 || moved != len(c.Records) {
+		t.Fatalf("Error: moved %d sessions but shard c has %d\n", moved, len(c.Records))
+	}
+
+	all, err := ss.All()
+	if err != nil {
+		t.Fatalf("Error: getting all sessions:%s\n", err.Error())
+	}
+
+	if len(all) != 100 {
+		t.Fatalf("Error: expected 100 sessions got %d\n", len(all))
+	}
+
+	for i, id := range ids {
+		s, err := ss.Get(id)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		if v, _ := s.Get("Key"); v != i {
+			t.Fatalf("Error: session %s lost after rebalance\n", id)
+		}
+	}
+
+	err = ss.Delete(ids[0])
+	if err != nil {
+		t.Fatalf("Error: deleting session:%s\n", err.Error())
+	}
+
+	if len(a.Records)+len(b.Records)+len(c.Records) != 99 {
+		t.Fatalf("Error: session not deleted\n")
+	}
+
+	_, err = ss.AddShard("c", c)
+	if err == nil {
+		t.Fatalf("Error: expected error adding duplicate shard\n")
+	}
+}
+
+func TestShardedStoreConformance(t *testing.T) {
+	sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+		return sessions.NewShardedStore(16, map[string]sessions.SessionStore{
+			"a": NewMapStore(),
+			"b": NewMapStore(),
+		})
+	})
+}
+
+// A MapStore whose Sets start failing after the first n.
+type FailingStore struct {
+	*MapStore
+	n int
+}
+
+func (t *FailingStore) Set(s sessions.Session) error {
+	if t.n <= 0 {
+		return errors.New("store full")
+	}
+	t.n--
+	return t.MapStore.Set(s)
+}
+
+func TestShardedStoreAddShardRollback(t *testing.T) {
+	a := NewMapStore()
+	ss := sessions.NewShardedStore(16, map[string]sessions.SessionStore{"a": a})
+
+	ids := make([]string, 0)
+	for i := 0; i < 100; i++ {
+		s, _ := sessions.NewDefaultSession()
+		s.Set("Key", i)
+		if err := ss.Set(s); err != nil {
+			t.Fatalf("Error: saving session:%s\n", err.Error())
+		}
+
+		id, _ := s.ID()
+		ids = append(ids, id)
+	}
+
+	// The new shard fails part way through the move.
+	b := &FailingStore{MapStore: NewMapStore(), n: 5}
+	if _, err := ss.AddShard("b", b); err == nil {
+		t.Fatalf("Error: expected error adding failing shard\n")
+	}
+
+	if len(b.Records) != 0 || len(a.Records) != 100 {
+		t.Fatalf("Error: sessions not put back a:%d b:%d\n", len(a.Records), len(b.Records))
+	}
+
+	for i, id := range ids {
+		s, err := ss.Get(id)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		if v, _ := s.Get("Key"); v != i {
+			t.Fatalf("Error: session %s lost after failed rebalance\n", id)
+		}
+	}
+
+	// The failed shard isn't left on the ring so it can be added again.
+	if _, err := ss.AddShard("b", NewMapStore()); err != nil {
+		t.Fatalf("Error: adding shard:%s\n", err.Error())
+	}
+}
@@ -0,0 +1,308 @@
+package sessions
+
+import (
+	"container/list"
+	"errors"
+	"sync"
+)
+
+type CacheMode int
+
+const (
+	// Sessions are saved to the inner store before Set returns.
+	WriteThrough CacheMode = iota
+
+	// Sessions are only saved to the inner store when they're evicted from the cache or the cache is flushed.
+	WriteBehind
+)
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// TieredStore keeps the most recently used sessions in memory in front of a (slower) persistent store.
+//
+// The cache holds the encoded session so every Get returns a session of its own,
+// requests sharing a session can't see each others unsaved changes.
+type TieredStore struct {
+	store SessionStore
+	mode  CacheMode
+	size  int
+
+	// Called with errors writing sessions pushed out of the cache by another session's Get or Set,
+	// they aren't that caller's errors. The sessions are kept and retried by the next eviction or Flush.
+	OnError func(error)
+
+	// Held while writing to the inner store so writes reach it in order.
+	// Reads never take it, a cache hit doesn't wait on a slow write.
+	writing sync.Mutex
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	stats   CacheStats
+
+	// Unsaved sessions pushed out of the cache, kept until they've been written.
+	evicting map[string]*tieredEntry
+
+	// Bumped by every Set and Delete, a Get only caches what it read if nothing changed while it was reading.
+	generation uint64
+}
+
+type tieredEntry struct {
+	id      string
+	encoded []byte
+	dirty   bool
+}
+
+// Create a TieredStore caching up to size sessions in front of store.
+// A size of 0 (or less) caches nothing.
+func NewTieredStore(store SessionStore, size int, mode CacheMode) *TieredStore {
+	if size < 0 {
+		size = 0
+	}
+
+	return &TieredStore{
+		store:    store,
+		mode:     mode,
+		size:     size,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		evicting: make(map[string]*tieredEntry),
+	}
+}
+
+func (t *TieredStore) Get(id string) (Session, error) {
+	if id == "" {
+		return t.store.Get(id)
+	}
+
+	t.mu.Lock()
+	if encoded, ok := t.cached(id); ok {
+		t.stats.Hits++
+		t.mu.Unlock()
+		return decodeSession(encoded)
+	}
+	t.stats.Misses++
+	generation := t.generation
+	t.mu.Unlock()
+
+	s, err := t.store.Get(id)
+	if err != nil {
+		return s, err
+	}
+
+	// Only cache sessions that exist, the store hands back a new session for unknown IDs.
+	if sid, err := s.ID(); err == nil && sid == id {
+		encoded, err := s.GobEncode()
+		if err != nil {
+			return s, err
+		}
+
+		t.mu.Lock()
+		// A Set or Delete while we were reading could have made s stale (or revoked it).
+		if t.generation == generation {
+			if _, ok := t.entries[id]; !ok {
+				t.add(id, encoded, false)
+			}
+		}
+		evicted := len(t.evicting) > 0
+		t.mu.Unlock()
+
+		if evicted {
+			t.drain()
+		}
+	}
+
+	return s, nil
+}
+
+func (t *TieredStore) Set(s Session) error {
+	id, err := s.ID()
+	if err != nil {
+		return err
+	}
+
+	encoded, err := s.GobEncode()
+	if err != nil {
+		return err
+	}
+
+	t.writing.Lock()
+	defer t.writing.Unlock()
+
+	if t.mode == WriteThrough {
+		if err := t.store.Set(s); err != nil {
+			return err
+		}
+	}
+
+	t.mu.Lock()
+	t.generation++
+
+	// This version replaces any older one waiting to be written.
+	delete(t.evicting, id)
+
+	if e, ok := t.entries[id]; ok {
+		entry := e.Value.(*tieredEntry)
+		entry.encoded = encoded
+		entry.dirty = t.mode == WriteBehind
+		t.lru.MoveToFront(e)
+	} else {
+		t.add(id, encoded, t.mode == WriteBehind)
+	}
+	t.mu.Unlock()
+
+	t.report(t.writeEvicted())
+	return nil
+}
+
+func (t *TieredStore) Delete(id string) error {
+	t.writing.Lock()
+	defer t.writing.Unlock()
+
+	t.mu.Lock()
+	t.generation++
+	if e, ok := t.entries[id]; ok {
+		t.lru.Remove(e)
+		delete(t.entries, id)
+	}
+	delete(t.evicting, id)
+	t.mu.Unlock()
+
+	return t.store.Delete(id)
+}
+
+// All sessions in the inner store, any unsaved sessions are flushed first.
+func (t *TieredStore) All() ([]Session, error) {
+	if err := t.Flush(); err != nil {
+		return nil, err
+	}
+
+	return t.store.All()
+}
+
+// Save all cached sessions that haven't reached the inner store yet.
+func (t *TieredStore) Flush() error {
+	t.writing.Lock()
+	defer t.writing.Unlock()
+
+	t.mu.Lock()
+	dirty := make([]*tieredEntry, 0)
+	for e := t.lru.Back(); e != nil; e = e.Prev() {
+		if entry := e.Value.(*tieredEntry); entry.dirty {
+			dirty = append(dirty, entry)
+		}
+	}
+	t.mu.Unlock()
+
+	for _, entry := range dirty {
+		if err := t.write(entry); err != nil {
+			return err
+		}
+	}
+
+	return t.writeEvicted()
+}
+
+// Snapshot of the cache statistics.
+func (t *TieredStore) Stats() CacheStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stats
+}
+
+// The encoded session if it's cached or waiting to be written.
+// Must be called with mu held.
+func (t *TieredStore) cached(id string) ([]byte, bool) {
+	if e, ok := t.entries[id]; ok {
+		t.lru.MoveToFront(e)
+		return e.Value.(*tieredEntry).encoded, true
+	}
+
+	if entry, ok := t.evicting[id]; ok {
+		return entry.encoded, true
+	}
+
+	return nil, false
+}
+
+// Must be called with mu held.
+// Unsaved sessions pushed out are moved to evicting, writeEvicted saves them.
+func (t *TieredStore) add(id string, encoded []byte, dirty bool) {
+	t.entries[id] = t.lru.PushFront(&tieredEntry{
+		id:      id,
+		encoded: encoded,
+		dirty:   dirty,
+	})
+
+	for t.lru.Len() > t.size {
+		e := t.lru.Back()
+		entry := e.Value.(*tieredEntry)
+		t.lru.Remove(e)
+		delete(t.entries, entry.id)
+		t.stats.Evictions++
+
+		if entry.dirty {
+			t.evicting[entry.id] = entry
+		}
+	}
+}
+
+func (t *TieredStore) drain() {
+	t.writing.Lock()
+	defer t.writing.Unlock()
+
+	t.report(t.writeEvicted())
+}
+
+func (t *TieredStore) report(err error) {
+	if err != nil && t.OnError != nil {
+		t.OnError(err)
+	}
+}
+
+// Must be called with writing held.
+// Sessions that fail stay in evicting, the rest are still written.
+func (t *TieredStore) writeEvicted() error {
+	t.mu.Lock()
+	evicted := make([]*tieredEntry, 0, len(t.evicting))
+	for _, entry := range t.evicting {
+		evicted = append(evicted, entry)
+	}
+	t.mu.Unlock()
+
+	var errs []error
+	for _, entry := range evicted {
+		if err := t.write(entry); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		t.mu.Lock()
+		if t.evicting[entry.id] == entry {
+			delete(t.evicting, entry.id)
+		}
+		t.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// Must be called with writing held, which stops the entry changing while it's written.
+func (t *TieredStore) write(entry *tieredEntry) error {
+	s, err := decodeSession(entry.encoded)
+	if err != nil {
+		return err
+	}
+
+	if err := t.store.Set(s); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	entry.dirty = false
+	t.mu.Unlock()
+	return nil
+}
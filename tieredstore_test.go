@@ -0,0 +1,316 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"testing"
+	"time"
+)
+
+func TestTieredStore(t *testing.T) {
+	inner := NewMapStore()
+	ts := sessions.NewTieredStore(inner, 1, sessions.WriteThrough)
+
+	s, err := sessions.NewDefaultSession()
+	if err != nil {
+		t.Fatalf("Error: creating new session:%s\n", err.Error())
+	}
+	s.Set("Key", "Value")
+
+	id, _ := s.ID()
+	err = ts.Set(s)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	if _, ok := inner.Records[id]; !ok {
+		t.Fatalf("Error: write through session not saved to inner store\n")
+	}
+
+	cached, err := ts.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	// Changes to a returned session don't leak into the cache.
+	cached.Set("Key", "Changed")
+
+	cached, err = ts.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if v, _ := cached.Get("Key"); v != "Value" {
+		t.Fatalf("Error: expected \"Value\" received \"%v\"\n", v)
+	}
+
+	// Miss for an unknown session.
+	_, err = ts.Get("Unknown")
+	if err != nil {
+		t.Fatalf("Error: getting unknown session:%s\n", err.Error())
+	}
+
+	stats := ts.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Fatalf("Error: expected 2 hits and 1 miss got %+v\n", stats)
+	}
+
+	err = ts.Delete(id)
+	if err != nil {
+		t.Fatalf("Error: deleting session:%s\n", err.Error())
+	}
+
+	if _, ok := inner.Records[id]; ok {
+		t.Fatalf("Error: session not deleted from inner store\n")
+	}
+
+	cached, err = ts.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting deleted session:%s\n", err.Error())
+	}
+
+	if keys, _ := cached.Keys(); len(keys) != 0 {
+		t.Fatalf("Error: deleted session still cached\n")
+	}
+}
+
+func TestTieredStoreWriteBehind(t *testing.T) {
+	inner := NewMapStore()
+	ts := sessions.NewTieredStore(inner, 1, sessions.WriteBehind)
+
+	first, _ := sessions.NewDefaultSession()
+	first.Set("Key", "First")
+	firstid, _ := first.ID()
+
+	second, _ := sessions.NewDefaultSession()
+	second.Set("Key", "Second")
+	secondid, _ := second.ID()
+
+	err := ts.Set(first)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	if len(inner.Records) != 0 {
+		t.Fatalf("Error: write behind session saved before eviction\n")
+	}
+
+	// Evicts the first session.
+	err = ts.Set(second)
+	if err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	if _, ok := inner.Records[firstid]; !ok {
+		t.Fatalf("Error: evicted session not saved\n")
+	}
+
+	if ts.Stats().Evictions != 1 {
+		t.Fatalf("Error: expected 1 eviction got %d\n", ts.Stats().Evictions)
+	}
+
+	err = ts.Flush()
+	if err != nil {
+		t.Fatalf("Error: flushing:%s\n", err.Error())
+	}
+
+	if _, ok := inner.Records[secondid]; !ok {
+		t.Fatalf("Error: flushed session not saved\n")
+	}
+}
+
+func TestTieredStoreConformance(t *testing.T) {
+	for _, mode := range []sessions.CacheMode{sessions.WriteThrough, sessions.WriteBehind} {
+		sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+			return sessions.NewTieredStore(NewMapStore(), 8, mode)
+		})
+	}
+}
+
+// A MapStore that stops in Get (after reading) or Set until the gate is opened.
+type GatedStore struct {
+	*MapStore
+	gateGet bool
+	gateSet bool
+	inside  chan struct{}
+	gate    chan struct{}
+}
+
+func NewGatedStore() *GatedStore {
+	return &GatedStore{
+		MapStore: NewMapStore(),
+		inside:   make(chan struct{}, 1),
+		gate:     make(chan struct{}),
+	}
+}
+
+func (t *GatedStore) Get(id string) (sessions.Session, error) {
+	s, err := t.MapStore.Get(id)
+	if t.gateGet {
+		t.inside <- struct{}{}
+		<-t.gate
+	}
+	return s, err
+}
+
+func (t *GatedStore) Set(s sessions.Session) error {
+	if t.gateSet {
+		t.inside <- struct{}{}
+		<-t.gate
+	}
+	return t.MapStore.Set(s)
+}
+
+func TestTieredStoreDeleteDuringMiss(t *testing.T) {
+	inner := NewGatedStore()
+
+	s, _ := sessions.NewDefaultSession()
+	s.Set("Key", "Value")
+	id, _ := s.ID()
+	inner.Set(s)
+
+	ts := sessions.NewTieredStore(inner, 8, sessions.WriteThrough)
+
+	// The session is read from the inner store, then revoked before the read finishes.
+	inner.gateGet = true
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if _, err := ts.Get(id); err != nil {
+			t.Errorf("Error: getting session:%s\n", err.Error())
+		}
+	}()
+
+	<-inner.inside
+	if err := ts.Delete(id); err != nil {
+		t.Fatalf("Error: deleting session:%s\n", err.Error())
+	}
+	close(inner.gate)
+	<-done
+	inner.gateGet = false
+
+	got, err := ts.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if gotid, _ := got.ID(); gotid == id {
+		t.Fatalf("Error: deleted session cached\n")
+	}
+}
+
+func TestTieredStoreGetDuringWrite(t *testing.T) {
+	inner := NewGatedStore()
+	ts := sessions.NewTieredStore(inner, 8, sessions.WriteThrough)
+
+	first, _ := sessions.NewDefaultSession()
+	first.Set("Key", "Value")
+	firstid, _ := first.ID()
+	if err := ts.Set(first); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	// A slow write doesn't hold up a cache hit.
+	inner.gateSet = true
+	second, _ := sessions.NewDefaultSession()
+	second.Set("Key", "Value")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ts.Set(second)
+	}()
+	<-inner.inside
+
+	got := make(chan error, 1)
+	go func() {
+		_, err := ts.Get(firstid)
+		got <- err
+	}()
+
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Error: cache hit waited on a write\n")
+	}
+
+	close(inner.gate)
+	<-done
+}
+
+func TestTieredStoreNoCache(t *testing.T) {
+	inner := NewMapStore()
+	ts := sessions.NewTieredStore(inner, -1, sessions.WriteBehind)
+
+	s, _ := sessions.NewDefaultSession()
+	s.Set("Key", "Value")
+	id, _ := s.ID()
+
+	if err := ts.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	// Nothing can be cached so the session is written straight away.
+	if _, ok := inner.Records[id]; !ok {
+		t.Fatalf("Error: session not saved\n")
+	}
+}
+
+func TestTieredStoreEvictionError(t *testing.T) {
+	inner := &FailingStore{MapStore: NewMapStore()}
+	ts := sessions.NewTieredStore(inner, 1, sessions.WriteBehind)
+
+	reported := make([]error, 0)
+	ts.OnError = func(err error) {
+		reported = append(reported, err)
+	}
+
+	first, _ := sessions.NewDefaultSession()
+	first.Set("Key", "First")
+	firstid, _ := first.ID()
+
+	second, _ := sessions.NewDefaultSession()
+	second.Set("Key", "Second")
+	secondid, _ := second.ID()
+
+	if err := ts.Set(first); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	// Pushing first out fails, that's not second's error.
+	if err := ts.Set(second); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	if len(reported) != 1 {
+		t.Fatalf("Error: expected 1 reported error got %v\n", reported)
+	}
+
+	// Kept until it's written.
+	s, err := ts.Get(firstid)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if v, _ := s.Get("Key"); v != "First" {
+		t.Fatalf("Error: expected \"First\" got \"%v\"\n", v)
+	}
+
+	if err := ts.Flush(); err == nil {
+		t.Fatalf("Error: expected flush to fail\n")
+	}
+
+	inner.n = 10
+	if err := ts.Flush(); err != nil {
+		t.Fatalf("Error: flushing:%s\n", err.Error())
+	}
+
+	for _, id := range []string{firstid, secondid} {
+		if _, ok := inner.Records[id]; !ok {
+			t.Fatalf("Error: session %s not saved\n", id)
+		}
+	}
+}
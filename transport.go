@@ -0,0 +1,186 @@
+package sessions
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	defaulttokenheader string = "X-Session-Token"
+)
+
+// IDTransport carries the session ID between the client and server.
+type IDTransport interface {
+	// The ID sent with the request, "" when there isn't one.
+	ReadID(r *http.Request) (string, error)
+
+	// Send the ID back to the client, an empty value tells the client to forget its ID.
+	WriteID(w http.ResponseWriter, r *http.Request, value string, expires time.Time) error
+}
+
+// CookieTransport keeps the ID in a cookie, it's what SessionInfo uses when no Transport is set.
+type CookieTransport struct {
+	Name   string
+	Path   string
+	Domain string
+
+	// The time MaxAge is worked out from, defaults to SystemClock.
+	Clock Clock
+}
+
+func (t CookieTransport) ReadID(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(t.Name)
+	if err != nil {
+		if err == http.ErrNoCookie {
+			return "", nil
+		} else {
+			return "", err
+		}
+	}
+
+	return cookie.Value, nil
+}
+
+func (t CookieTransport) WriteID(w http.ResponseWriter, r *http.Request, value string, expires time.Time) error {
+	cookie := &http.Cookie{
+		Name: t.Name,
+	}
+
+	if t.Path != "" {
+		cookie.Path = t.Path
+	} else {
+		cookie.Path = "/"
+	}
+
+	if t.Domain != "" {
+		cookie.Domain = t.Domain
+	}
+
+	now := now(t.Clock)
+
+	if value == "" {
+		//Expire the Cookie.
+		cookie.Value = ""
+		cookie.Expires = now
+		cookie.MaxAge = -1
+
+	} else {
+
+		cookie.Value = value
+		cookie.Expires = expires
+
+		if int64(^uint(0)>>1) < int64(expires.Sub(now).Seconds()) {
+			cookie.MaxAge = int(^uint(0) >> 1)
+		} else {
+			cookie.MaxAge = int(expires.Sub(now).Seconds())
+		}
+
+	}
+
+	//Send the cookie back
+	http.SetCookie(w, cookie)
+	return nil
+}
+
+// HeaderTransport reads the ID from a request header and returns it in the same response header.
+type HeaderTransport struct {
+	// The header name, defaults to X-Session-Token.
+	Name string
+}
+
+func (t HeaderTransport) ReadID(r *http.Request) (string, error) {
+	return r.Header.Get(headerName(t.Name)), nil
+}
+
+func (t HeaderTransport) WriteID(w http.ResponseWriter, r *http.Request, value string, expires time.Time) error {
+	w.Header().Set(headerName(t.Name), value)
+	return nil
+}
+
+// BearerTransport reads the ID from an "Authorization: Bearer" header.
+// There's no response equivalent so the ID is returned in a response header.
+type BearerTransport struct {
+	// The response header, defaults to X-Session-Token.
+	Header string
+}
+
+func (t BearerTransport) ReadID(r *http.Request) (string, error) {
+	auth := r.Header.Get("Authorization")
+	if len(auth) > 7 && strings.EqualFold(auth[:7], "Bearer ") {
+		return strings.TrimSpace(auth[7:]), nil
+	}
+	return "", nil
+}
+
+func (t BearerTransport) WriteID(w http.ResponseWriter, r *http.Request, value string, expires time.Time) error {
+	w.Header().Set(headerName(t.Header), value)
+	return nil
+}
+
+// QueryTransport reads the ID from a query parameter, the ID is returned in a response header.
+// IDs in URLs end up in logs and Referer headers, prefer the other transports where the client allows.
+type QueryTransport struct {
+	Parameter string
+
+	// The response header, defaults to X-Session-Token.
+	Header string
+}
+
+func (t QueryTransport) ReadID(r *http.Request) (string, error) {
+	return r.URL.Query().Get(t.Parameter), nil
+}
+
+func (t QueryTransport) WriteID(w http.ResponseWriter, r *http.Request, value string, expires time.Time) error {
+	w.Header().Set(headerName(t.Header), value)
+	return nil
+}
+
+// TransportChain tries each transport in turn, the first to find an ID wins.
+// The ID is written back with the transport it came in on,
+// when the request had no ID it's written with all of them as there's no telling what the client supports.
+type TransportChain []IDTransport
+
+func (t TransportChain) ReadID(r *http.Request) (string, error) {
+	_, id, err := t.find(r)
+	return id, err
+}
+
+func (t TransportChain) WriteID(w http.ResponseWriter, r *http.Request, value string, expires time.Time) error {
+	transport, _, err := t.find(r)
+	if err != nil {
+		return err
+	}
+
+	if transport != nil {
+		return transport.WriteID(w, r, value, expires)
+	}
+
+	for _, transport := range t {
+		if err := transport.WriteID(w, r, value, expires); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t TransportChain) find(r *http.Request) (IDTransport, string, error) {
+	for _, transport := range t {
+		id, err := transport.ReadID(r)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if id != "" {
+			return transport, id, nil
+		}
+	}
+	return nil, "", nil
+}
+
+func headerName(name string) string {
+	if name == "" {
+		return defaulttokenheader
+	}
+	return name
+}
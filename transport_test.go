@@ -0,0 +1,187 @@
+package sessions_test
+
+import (
+	"github.com/d2g/sessions"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestIDTransports(t *testing.T) {
+	checks := []struct {
+		name      string
+		transport sessions.IDTransport
+		send      func(*http.Request, string)
+		header    string
+	}{
+		{"header", sessions.HeaderTransport{}, func(r *http.Request, id string) {
+			r.Header.Set("X-Session-Token", id)
+		}, "X-Session-Token"},
+		{"bearer", sessions.BearerTransport{Header: "X-Token"}, func(r *http.Request, id string) {
+			r.Header.Set("Authorization", "Bearer "+id)
+		}, "X-Token"},
+		{"query", sessions.QueryTransport{Parameter: "sid"}, func(r *http.Request, id string) {
+			r.URL.RawQuery = "sid=" + id
+		}, "X-Session-Token"},
+	}
+
+	for _, c := range checks {
+		r := httptest.NewRequest("GET", "/", nil)
+
+		id, err := c.transport.ReadID(r)
+		if err != nil || id != "" {
+			t.Fatalf("Error: %s expected no id got %q %v\n", c.name, id, err)
+		}
+
+		c.send(r, "ABC")
+
+		id, err = c.transport.ReadID(r)
+		if err != nil {
+			t.Fatalf("Error: %s reading id:%s\n", c.name, err.Error())
+		}
+
+		if id != "ABC" {
+			t.Fatalf("Error: %s expected ABC got %s\n", c.name, id)
+		}
+
+		w := httptest.NewRecorder()
+		if err := c.transport.WriteID(w, r, "DEF", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Error: %s writing id:%s\n", c.name, err.Error())
+		}
+
+		if got := w.Header().Get(c.header); got != "DEF" {
+			t.Fatalf("Error: %s expected DEF in %s got %q\n", c.name, c.header, got)
+		}
+	}
+}
+
+func TestTransportChain(t *testing.T) {
+	chain := sessions.TransportChain{
+		sessions.CookieTransport{Name: "SESSIONID"},
+		sessions.BearerTransport{},
+	}
+
+	// The ID is written back the way it came.
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer ABC")
+
+	w := httptest.NewRecorder()
+	if err := chain.WriteID(w, r, "ABC", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Error: writing id:%s\n", err.Error())
+	}
+
+	if w.Header().Get("X-Session-Token") != "ABC" || len(w.Result().Cookies()) != 0 {
+		t.Fatalf("Error: expected only the token header got %v\n", w.Header())
+	}
+
+	// Without an ID it's written with every transport.
+	r = httptest.NewRequest("GET", "/", nil)
+
+	w = httptest.NewRecorder()
+	if err := chain.WriteID(w, r, "ABC", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Error: writing id:%s\n", err.Error())
+	}
+
+	if w.Header().Get("X-Session-Token") != "ABC" || len(w.Result().Cookies()) != 1 {
+		t.Fatalf("Error: expected the token header and cookie got %v\n", w.Header())
+	}
+}
+
+func TestSessionInfoTransport(t *testing.T) {
+	si := &sessions.SessionInfo{}
+	si.Timeout = time.Hour
+	si.Store = NewMapStore()
+	si.Transport = sessions.HeaderTransport{}
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := si.GetSession(r)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		v, err := s.Get("Visits")
+		if err != nil {
+			t.Fatalf("Error: getting value from session:%s\n", err.Error())
+		}
+
+		visits, _ := v.(int)
+		s.Set("Visits", visits+1)
+
+		// The ID header has to go out with the body.
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+	id := w.Result().Header.Get("X-Session-Token")
+	if id == "" {
+		t.Fatalf("Error: session id not returned\n")
+	}
+
+	if len(w.Result().Cookies()) != 0 {
+		t.Fatalf("Error: unexpected cookies %v\n", w.Result().Cookies())
+	}
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("X-Session-Token", id)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Result().Header.Get("X-Session-Token") != id {
+		t.Fatalf("Error: expected session %s got %s\n", id, w.Result().Header.Get("X-Session-Token"))
+	}
+
+	s, err := si.Store.Get(id)
+	if err != nil {
+		t.Fatalf("Error: loading session:%s\n", err.Error())
+	}
+
+	v, err := s.Get("Visits")
+	if err != nil {
+		t.Fatalf("Error: getting value from session:%s\n", err.Error())
+	}
+
+	if v != 2 {
+		t.Fatalf("Error: expected 2 visits got %v\n", v)
+	}
+}
+
+func TestSessionInfoBearerTransport(t *testing.T) {
+	si := &sessions.SessionInfo{}
+	si.Timeout = time.Hour
+	si.Store = NewMapStore()
+	si.Transport = sessions.BearerTransport{}
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s, err := si.GetSession(r)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		s.Set("User", "alice")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("POST", "/login", nil))
+
+	id := w.Result().Header.Get("X-Session-Token")
+	if id == "" || w.Body.String() != `{"ok":true}` {
+		t.Fatalf("Error: session id not returned with the body, headers %v\n", w.Result().Header)
+	}
+
+	// The API client sends it back.
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set("Authorization", "Bearer "+id)
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Result().Header.Get("X-Session-Token") != id {
+		t.Fatalf("Error: expected session %s got %s\n", id, w.Result().Header.Get("X-Session-Token"))
+	}
+}
@@ -0,0 +1,58 @@
+package unqlitesessionstore
+
+import (
+	"github.com/d2g/sessions"
+	"time"
+)
+
+// Metadata describes a stored session without its values.
+type Metadata struct {
+	ID      string
+	Expires time.Time
+	Keys    int
+
+	// The user the session is bound to, empty when it isn't bound.
+	User string
+}
+
+// The metadata of the stored sessions accepted by filter (a nil filter accepts everything).
+// Every session is decoded (and corrupt records quarantined) as they are by All, there's no index behind the filter.
+//
+// JX9 queries aren't supported, unqlitego doesn't expose unqlite's JX9 virtual machine.
+func (t *UnqliteStore) Filter(filter func(Metadata) bool) ([]Metadata, error) {
+	all, err := t.All()
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]Metadata, 0)
+	for _, s := range all {
+		id, err := s.ID()
+		if err != nil {
+			return nil, err
+		}
+
+		keys, err := s.Keys()
+		if err != nil {
+			return nil, err
+		}
+
+		user, err := sessions.SessionUser(s)
+		if err != nil {
+			return nil, err
+		}
+
+		m := Metadata{
+			ID:      id,
+			Expires: s.Expiry(),
+			Keys:    len(keys),
+			User:    user,
+		}
+
+		if filter == nil || filter(m) {
+			result = append(result, m)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,188 @@
+package unqlitesessionstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"github.com/d2g/sessions"
+	"github.com/d2g/unqlitego"
+)
+
+const (
+	// Quarantined records are kept in the same database under this prefix.
+	quarantineprefix string = "quarantine:"
+)
+
+// The records that can't be decoded, they're left in place.
+func (t *UnqliteStore) Corrupt() ([]sessions.QuarantinedRecord, error) {
+	_, corrupt, err := t.scan()
+	return corrupt, err
+}
+
+// Move the records that can't be decoded to the quarantine, returns the number moved.
+// All does this itself, this is for running it without keeping every session.
+func (t *UnqliteStore) QuarantineCorrupt() (int, error) {
+	if t.mode == OpenReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	_, corrupt, err := t.scan()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, record := range corrupt {
+		moved, err := t.quarantine(record)
+		if err != nil {
+			return n, err
+		}
+
+		if moved {
+			n++
+		}
+	}
+
+	return n, nil
+}
+
+// Move the record to the quarantine, unless it's been saved again since it was read.
+func (t *UnqliteStore) quarantine(record sessions.QuarantinedRecord) (bool, error) {
+	current, err := t.collection.Fetch([]byte(record.ID))
+	if err != nil {
+		if err == unqlitego.UnQLiteError(-6) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	if !bytes.Equal(current, record.Data) {
+		return false, nil
+	}
+
+	buf := new(bytes.Buffer)
+	enc := gob.NewEncoder(buf)
+	if err := enc.Encode(record); err != nil {
+		return false, err
+	}
+
+	if err := t.collection.Store([]byte(quarantineprefix+record.ID), buf.Bytes()); err != nil {
+		return false, err
+	}
+
+	t.logger().Warn("quarantined corrupt session", "session_id", record.ID, "error", record.Error)
+	return true, t.collection.Delete([]byte(record.ID))
+}
+
+func (t *UnqliteStore) Quarantined() ([]sessions.QuarantinedRecord, error) {
+	records := make([]sessions.QuarantinedRecord, 0)
+
+	keys, err := t.quarantinedKeys()
+	if err != nil {
+		return records, err
+	}
+
+	for _, key := range keys {
+		value, err := t.collection.Fetch(key)
+		if err != nil {
+			return records, err
+		}
+
+		record := sessions.QuarantinedRecord{}
+		dec := gob.NewDecoder(bytes.NewBuffer(value))
+		if err := dec.Decode(&record); err != nil {
+			return records, err
+		}
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// Decode the quarantined record again, on success it's restored as a session.
+func (t *UnqliteStore) RetryQuarantined(id string) (sessions.Session, error) {
+	value, err := t.collection.Fetch([]byte(quarantineprefix + id))
+	if err != nil {
+		if err == unqlitego.UnQLiteError(-6) {
+			return nil, errors.New("unqlitesessionstore: session \"" + id + "\" not quarantined")
+		}
+		return nil, err
+	}
+
+	record := sessions.QuarantinedRecord{}
+	dec := gob.NewDecoder(bytes.NewBuffer(value))
+	if err := dec.Decode(&record); err != nil {
+		return nil, err
+	}
+
+	s, err := sessions.NewDefaultSession()
+	if err != nil {
+		return nil, err
+	}
+
+	dec = gob.NewDecoder(bytes.NewBuffer(record.Data))
+	if err := dec.Decode(&s); err != nil {
+		return nil, err
+	}
+
+	if err := t.Set(s); err != nil {
+		return nil, err
+	}
+
+	return s, t.collection.Delete([]byte(quarantineprefix + id))
+}
+
+func (t *UnqliteStore) PurgeQuarantine() (int, error) {
+	if t.mode == OpenReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	keys, err := t.quarantinedKeys()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, key := range keys {
+		if err := t.collection.Delete(key); err != nil {
+			return i, err
+		}
+	}
+
+	return len(keys), nil
+}
+
+func (t *UnqliteStore) quarantinedKeys() ([][]byte, error) {
+	keys := make([][]byte, 0)
+
+	cursor, err := t.collection.NewCursor()
+	if err != nil {
+		return keys, err
+	}
+	defer cursor.Close()
+
+	err = cursor.First()
+	if err != nil {
+		//You Get -28 When There are no records.
+		if err == unqlitego.UnQLiteError(-28) {
+			return keys, nil
+		}
+		return keys, err
+	}
+
+	for cursor.IsValid() {
+		key, err := cursor.Key()
+		if err != nil {
+			return keys, err
+		}
+
+		if bytes.HasPrefix(key, []byte(quarantineprefix)) {
+			keys = append(keys, key)
+		}
+
+		if err := cursor.Next(); err != nil {
+			break
+		}
+	}
+
+	return keys, nil
+}
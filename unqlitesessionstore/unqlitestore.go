@@ -3,17 +3,62 @@ package unqlitesessionstore
 import (
 	"bytes"
 	"encoding/gob"
+	"errors"
 	"github.com/d2g/sessions"
 	"github.com/d2g/unqlitego"
-	"log"
+	"log/slog"
+	"os"
+	"time"
 )
 
-type unqliteStore struct {
+type UnqliteStore struct {
 	collection *unqlitego.Database
+	mode       Mode
+
+	// Where messages are logged, defaults to slog.Default().
+	Logger *slog.Logger
+}
+
+type Mode int
+
+const (
+	// Open the database, creating it if it doesn't exist.
+	OpenCreate Mode = iota
+
+	// Open an existing database.
+	OpenReadWrite
+
+	// Open an existing database, Set and Delete return ErrReadOnly.
+	// unqlitego always opens the file for writing so this is enforced by the store.
+	OpenReadOnly
+
+	// A private in memory database, the filename is ignored.
+	OpenInMemory
+)
+
+var (
+	ErrReadOnly = errors.New("unqlitesessionstore: store opened read only")
+)
+
+// Open (or create) the database in filename.
+func New(filename string) (*UnqliteStore, error) {
+	return Open(filename, OpenCreate)
 }
 
-func New(filename string) (*unqliteStore, error) {
-	store := new(unqliteStore)
+// Open the database in filename with the given mode.
+func Open(filename string, mode Mode) (*UnqliteStore, error) {
+	switch mode {
+	case OpenReadWrite, OpenReadOnly:
+		if _, err := os.Stat(filename); err != nil {
+			return nil, err
+		}
+	case OpenInMemory:
+		filename = ":mem:"
+	}
+
+	store := &UnqliteStore{
+		mode: mode,
+	}
 
 	var err error
 	store.collection, err = unqlitego.NewDatabase(filename)
@@ -24,7 +69,19 @@ func New(filename string) (*unqliteStore, error) {
 	return store, nil
 }
 
-func (t *unqliteStore) Get(id string) (sessions.Session, error) {
+// Close the underlying database.
+func (t *UnqliteStore) Close() error {
+	return t.collection.Close()
+}
+
+func (t *UnqliteStore) logger() *slog.Logger {
+	if t.Logger != nil {
+		return t.Logger
+	}
+	return slog.Default()
+}
+
+func (t *UnqliteStore) Get(id string) (sessions.Session, error) {
 	var err error
 
 	s, err := sessions.NewDefaultSession()
@@ -53,7 +110,11 @@ func (t *unqliteStore) Get(id string) (sessions.Session, error) {
 	return s, err
 }
 
-func (t *unqliteStore) Set(s sessions.Session) error {
+func (t *UnqliteStore) Set(s sessions.Session) error {
+	if t.mode == OpenReadOnly {
+		return ErrReadOnly
+	}
+
 	sessionid, err := s.ID()
 	if err != nil {
 		return err
@@ -67,41 +128,67 @@ func (t *unqliteStore) Set(s sessions.Session) error {
 
 	err = t.collection.Store([]byte(sessionid), buf.Bytes())
 	if err != nil {
-		log.Println("Error: " + err.Error())
+		t.logger().Error("saving session", "session_id", sessionid, "error", err)
 		return err
 	}
 
 	return nil
 }
 
-func (t *unqliteStore) Delete(id string) error {
+func (t *UnqliteStore) Delete(id string) error {
+	if t.mode == OpenReadOnly {
+		return ErrReadOnly
+	}
+
 	err := t.collection.DeleteObject(id)
 
 	if err != nil {
-		log.Println("Error: Deleting Session from Datastore")
+		t.logger().Error("deleting session", "session_id", id, "error", err)
 	}
 
 	return err
 }
 
-func (t *unqliteStore) All() ([]sessions.Session, error) {
+// All the sessions in the store, records that can't be decoded are skipped and quarantined.
+// A read only store can't move them, they're skipped and logged.
+func (t *UnqliteStore) All() ([]sessions.Session, error) {
+	s, corrupt, err := t.scan()
+	if err != nil {
+		return s, err
+	}
+
+	for _, record := range corrupt {
+		if t.mode == OpenReadOnly {
+			t.logger().Warn("skipping corrupt session", "session_id", record.ID, "error", record.Error)
+			continue
+		}
+
+		if _, err := t.quarantine(record); err != nil {
+			return s, err
+		}
+	}
+	return s, nil
+}
 
+// Decode every session, returning the records that couldn't be decoded separately.
+func (t *UnqliteStore) scan() ([]sessions.Session, []sessions.QuarantinedRecord, error) {
 	s := make([]sessions.Session, 0, 0)
+	corrupt := make([]sessions.QuarantinedRecord, 0)
 
 	cursor, err := t.collection.NewCursor()
-	defer cursor.Close()
-
 	if err != nil {
-		return s, err
+		return s, corrupt, err
 	}
 
 	err = cursor.First()
 	if err != nil {
+		cursor.Close()
+
 		//You Get -28 When There are no records.
 		if err == unqlitego.UnQLiteError(-28) {
-			return s, nil
+			return s, corrupt, nil
 		} else {
-			return s, err
+			return s, corrupt, err
 		}
 	}
 
@@ -110,32 +197,35 @@ func (t *unqliteStore) All() ([]sessions.Session, error) {
 			break
 		}
 
-		session, err := sessions.NewDefaultSession()
-		if err != nil {
-			return s, err
-		}
-
-		value, err := cursor.Value()
-
+		key, err := cursor.Key()
 		if err != nil {
-			log.Println("Error: Cursor Get Value Error:" + err.Error())
-		} else {
+			t.logger().Error("reading key from cursor", "error", err)
+		} else if !bytes.HasPrefix(key, []byte(quarantineprefix)) {
+			session, err := sessions.NewDefaultSession()
+			if err != nil {
+				cursor.Close()
+				return s, corrupt, err
+			}
 
-			dec := gob.NewDecoder(bytes.NewBuffer(value))
-			if err := dec.Decode(&session); err != nil {
-				key, err := cursor.Key()
-				if err != nil {
-					log.Println("Error: Cursor Get Key Error:" + err.Error())
-					cursor.Delete()
-					continue
+			value, err := cursor.Value()
+
+			if err != nil {
+				t.logger().Error("reading session from cursor", "session_id", string(key), "error", err)
+			} else {
+
+				dec := gob.NewDecoder(bytes.NewBuffer(value))
+				if err := dec.Decode(&session); err != nil {
+					//Broken Session.
+					corrupt = append(corrupt, sessions.QuarantinedRecord{
+						ID:    string(key),
+						Data:  value,
+						Error: err.Error(),
+						Time:  time.Now(),
+					})
 				} else {
-					log.Println("Error: Invalid Session in Datastore:" + string(key))
-					cursor.Delete()
-					continue
+					s = append(s, session)
 				}
 			}
-
-			s = append(s, session)
 		}
 
 		err = cursor.Next()
@@ -146,7 +236,8 @@ func (t *unqliteStore) All() ([]sessions.Session, error) {
 
 	err = cursor.Close()
 	if err != nil {
-		log.Println("Error Closing Sursor:" + err.Error())
+		t.logger().Error("closing cursor", "error", err)
 	}
-	return s, err
+
+	return s, corrupt, err
 }
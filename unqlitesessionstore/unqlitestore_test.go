@@ -0,0 +1,117 @@
+package unqlitesessionstore_test
+
+import (
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"github.com/d2g/sessions/unqlitesessionstore"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUnqliteStoreConformance(t *testing.T) {
+	sessionstest.RunStoreConformance(t, func() sessions.SessionStore {
+		store, err := unqlitesessionstore.Open("", unqlitesessionstore.OpenInMemory)
+		if err != nil {
+			t.Fatalf("Error: opening store:%s\n", err.Error())
+		}
+		t.Cleanup(func() {
+			store.Close()
+		})
+		return store
+	})
+}
+
+func TestUnqliteStoreOpenModes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.db")
+
+	// Only OpenCreate makes a new file.
+	for _, mode := range []unqlitesessionstore.Mode{unqlitesessionstore.OpenReadWrite, unqlitesessionstore.OpenReadOnly} {
+		if _, err := unqlitesessionstore.Open(path, mode); err == nil {
+			t.Fatalf("Error: expected opening a missing file with mode %d to fail\n", mode)
+		}
+	}
+
+	store, err := unqlitesessionstore.Open(path, unqlitesessionstore.OpenCreate)
+	if err != nil {
+		t.Fatalf("Error: creating store:%s\n", err.Error())
+	}
+
+	s, _ := sessions.NewDefaultSession()
+	s.Set("Key", "Value")
+	id, _ := s.ID()
+
+	if err := store.Set(s); err != nil {
+		t.Fatalf("Error: saving session:%s\n", err.Error())
+	}
+
+	if err := store.Close(); err != nil {
+		t.Fatalf("Error: closing store:%s\n", err.Error())
+	}
+
+	// Closed and reopened the session is still there.
+	store, err = unqlitesessionstore.Open(path, unqlitesessionstore.OpenReadOnly)
+	if err != nil {
+		t.Fatalf("Error: opening store read only:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	got, err := store.Get(id)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	if v, _ := got.Get("Key"); v != "Value" {
+		t.Fatalf("Error: expected \"Value\" got \"%v\"\n", v)
+	}
+
+	if err := store.Set(s); err != unqlitesessionstore.ErrReadOnly {
+		t.Fatalf("Error: expected read only error saving got %v\n", err)
+	}
+
+	if err := store.Delete(id); err != unqlitesessionstore.ErrReadOnly {
+		t.Fatalf("Error: expected read only error deleting got %v\n", err)
+	}
+
+	if _, err := store.QuarantineCorrupt(); err != unqlitesessionstore.ErrReadOnly {
+		t.Fatalf("Error: expected read only error quarantining got %v\n", err)
+	}
+
+	if _, err := store.PurgeQuarantine(); err != unqlitesessionstore.ErrReadOnly {
+		t.Fatalf("Error: expected read only error purging got %v\n", err)
+	}
+}
+
+func TestUnqliteStoreFilter(t *testing.T) {
+	store, err := unqlitesessionstore.Open("", unqlitesessionstore.OpenInMemory)
+	if err != nil {
+		t.Fatalf("Error: opening store:%s\n", err.Error())
+	}
+	defer store.Close()
+
+	expiry := time.Now().Add(time.Hour).Round(0)
+	for _, user := range []string{"alice", "bob", ""} {
+		s, _ := sessions.NewDefaultSession()
+		s.Set("Key", "Value")
+		sessions.BindUser(s, user)
+		s.SetExpiry(expiry)
+		store.Set(s)
+	}
+
+	all, err := store.Filter(nil)
+	if err != nil || len(all) != 3 {
+		t.Fatalf("Error: expected 3 sessions got %d (%v)\n", len(all), err)
+	}
+
+	alice, err := store.Filter(func(m unqlitesessionstore.Metadata) bool {
+		return m.User == "alice"
+	})
+	if err != nil || len(alice) != 1 {
+		t.Fatalf("Error: expected 1 session for alice got %d (%v)\n", len(alice), err)
+	}
+
+	// Key and the user binding.
+	if alice[0].ID == "" || alice[0].Keys != 2 || !alice[0].Expires.Equal(expiry) {
+		t.Fatalf("Error: unexpected metadata %+v\n", alice[0])
+	}
+}
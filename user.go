@@ -0,0 +1,282 @@
+package sessions
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+)
+
+const (
+	userkey string = "sessions.user"
+)
+
+type SessionLimitPolicy int
+
+const (
+	// Refuse the new login with ErrSessionLimit.
+	RejectNewSession SessionLimitPolicy = iota
+
+	// Delete the user's least recently used sessions to make room.
+	EvictOldestSession
+)
+
+var (
+	ErrSessionLimit = errors.New("sessions: user has too many active sessions")
+)
+
+// UserIndex is implemented by stores that index sessions by the user they're bound to.
+type UserIndex interface {
+	// The IDs of the sessions bound to the user.
+	UserSessions(user string) ([]string, error)
+}
+
+// Bind the session to a user (i.e. after login), an empty user removes the binding.
+func BindUser(s Session, user string) error {
+	if user == "" {
+		return s.Delete(userkey)
+	}
+	return s.Set(userkey, user)
+}
+
+// The user the session is bound to, empty when the session isn't bound.
+func SessionUser(s Session) (string, error) {
+	v, err := s.Get(userkey)
+	if err != nil {
+		return "", err
+	}
+
+	user, _ := v.(string)
+	return user, nil
+}
+
+// The IDs of the sessions bound to user, using the store's index if it has one.
+func userSessions(store SessionStore, user string) ([]string, error) {
+	if user == "" {
+		return nil, errors.New("sessions: empty user")
+	}
+
+	if index, ok := store.(UserIndex); ok {
+		return index.UserSessions(user)
+	}
+
+	return scanUserSessions(store, user)
+}
+
+// The IDs of the sessions bound to user, found by reading every session.
+func scanUserSessions(store SessionStore, user string) ([]string, error) {
+	all, err := store.All()
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0)
+	for _, s := range all {
+		u, err := SessionUser(s)
+		if err != nil {
+			return nil, err
+		}
+
+		if u != user {
+			continue
+		}
+
+		id, err := s.ID()
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// All the sessions bound to user.
+func (t *SessionInfo) UserSessions(user string) ([]Session, error) {
+	ids, err := userSessions(t.Store, user)
+	if err != nil {
+		return nil, err
+	}
+
+	all := make([]Session, 0, len(ids))
+	for _, id := range ids {
+		s, err := t.Store.Get(id)
+		if err != nil {
+			return nil, err
+		}
+
+		// The index can be behind the store, make sure it's still the user's session.
+		if sid, err := s.ID(); err != nil || sid != id {
+			continue
+		}
+
+		if u, err := SessionUser(s); err != nil || u != user {
+			continue
+		}
+
+		all = append(all, s)
+	}
+
+	return all, nil
+}
+
+// Delete every session bound to user (log out everywhere), returns the number of sessions deleted.
+func (t *SessionInfo) RevokeUserSessions(user string) (int, error) {
+	all, err := t.UserSessions(user)
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for _, s := range all {
+		id, err := s.ID()
+		if err != nil {
+			return n, err
+		}
+
+		if err := t.Hooks.destroy(t.Store, id, s, nil); err != nil {
+			return n, err
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+// Bind the request's session to user, enforcing MaxUserSessions.
+//
+// The session is moved to a new ID so an ID planted on the client before login (session fixation)
+// isn't logged in: the values are copied to a new session, the old record is deleted and the CSRF
+// secret is dropped. The old ID stops working straight away, the new one is sent by GetHandler's handler
+// when the response is started (the first WriteHeader or Write, i.e. http.Redirect) so Login has to be called
+// inside it and before anything is written.
+//
+// The limit is checked against the saved sessions, logins racing each other (before either is saved)
+// can both get through and leave the user one over MaxUserSessions.
+func (t *SessionInfo) Login(request *http.Request, user string) error {
+	session, err := t.GetSession(request)
+	if err != nil {
+		return err
+	}
+
+	if t.MaxUserSessions > 0 {
+		if err := t.limitUserSessions(session, user); err != nil {
+			return err
+		}
+	}
+
+	renewed, err := t.renew(session)
+	if err != nil {
+		return err
+	}
+
+	if err := renewed.Delete(csrfkey); err != nil {
+		return err
+	}
+
+	if err := BindUser(renewed, user); err != nil {
+		return err
+	}
+
+	oldid, err := session.ID()
+	if err != nil {
+		return err
+	}
+
+	if err := t.Store.Delete(oldid); err != nil {
+		return err
+	}
+
+	t.SetSession(request, renewed)
+	return nil
+}
+
+// A copy of the session under a new ID.
+func (t *SessionInfo) renew(session Session) (Session, error) {
+	var renewed Session
+	var err error
+
+	if t.IDGenerator != nil {
+		renewed, err = NewSessionWithGenerator(t.IDGenerator)
+	} else {
+		renewed, err = NewDefaultSession()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := session.Keys()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, k := range keys {
+		v, err := session.Get(k)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := renewed.Set(k, v); err != nil {
+			return nil, err
+		}
+	}
+
+	renewed.SetExpiry(session.Expiry())
+	return renewed, nil
+}
+
+// Make room for one more of the user's sessions.
+func (t *SessionInfo) limitUserSessions(session Session, user string) error {
+	id, err := session.ID()
+	if err != nil {
+		return err
+	}
+
+	all, err := t.UserSessions(user)
+	if err != nil {
+		return err
+	}
+
+	now := now(t.Clock)
+	active := make([]Session, 0, len(all))
+	for _, s := range all {
+		sid, err := s.ID()
+		if err != nil {
+			return err
+		}
+
+		// Logging in again on the same session doesn't take another slot.
+		if sid == id {
+			return nil
+		}
+
+		if s.Expiry().After(now) {
+			active = append(active, s)
+		}
+	}
+
+	if len(active) < t.MaxUserSessions {
+		return nil
+	}
+
+	if t.SessionLimitPolicy != EvictOldestSession {
+		return ErrSessionLimit
+	}
+
+	// Sessions are extended whenever they're used, the earliest expiry was used longest ago.
+	sort.Slice(active, func(i, j int) bool {
+		return active[i].Expiry().Before(active[j].Expiry())
+	})
+
+	for _, s := range active[:len(active)-t.MaxUserSessions+1] {
+		sid, err := s.ID()
+		if err != nil {
+			return err
+		}
+
+		if err := t.Hooks.destroy(t.Store, sid, s, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
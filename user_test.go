@@ -0,0 +1,248 @@
+package sessions_test
+
+import (
+	"bytes"
+	"github.com/d2g/sessions"
+	"github.com/d2g/sessions/sessionstest"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestUserSessions(t *testing.T) {
+	inner := NewMapStore()
+	store, err := sessions.NewEncryptedStore(inner, "key", map[string][]byte{"key": bytes.Repeat([]byte{1}, 16)})
+	if err != nil {
+		t.Fatalf("Error: creating encrypted store:%s\n", err.Error())
+	}
+
+	si := sessions.SessionInfo{}
+	si.Store = store
+
+	destroyed := 0
+	si.Hooks.OnDestroy = func(e sessions.SessionEvent) {
+		if u, _ := sessions.SessionUser(e.Session); u != "alice" {
+			t.Fatalf("Error: expected destroyed session for alice got %q\n", u)
+		}
+		destroyed++
+	}
+
+	for _, user := range []string{"alice", "alice", "bob", ""} {
+		s, err := sessions.NewDefaultSession()
+		if err != nil {
+			t.Fatalf("Error: creating new session:%s\n", err.Error())
+		}
+
+		err = sessions.BindUser(s, user)
+		if err != nil {
+			t.Fatalf("Error: binding user:%s\n", err.Error())
+		}
+
+		if u, _ := sessions.SessionUser(s); u != user {
+			t.Fatalf("Error: expected user \"%s\" got \"%s\"\n", user, u)
+		}
+
+		store.Set(s)
+	}
+
+	alice, err := si.UserSessions("alice")
+	if err != nil {
+		t.Fatalf("Error: getting user sessions:%s\n", err.Error())
+	}
+
+	if len(alice) != 2 {
+		t.Fatalf("Error: expected 2 sessions for alice got %d\n", len(alice))
+	}
+
+	n, err := si.RevokeUserSessions("alice")
+	if err != nil {
+		t.Fatalf("Error: revoking user sessions:%s\n", err.Error())
+	}
+
+	if n != 2 || len(inner.Records) != 2 || destroyed != 2 {
+		t.Fatalf("Error: expected 2 sessions revoked got %d (%d destroy events)\n", n, destroyed)
+	}
+
+	bob, err := si.UserSessions("bob")
+	if err != nil || len(bob) != 1 {
+		t.Fatalf("Error: expected 1 session for bob got %d (%v)\n", len(bob), err)
+	}
+
+	// Unbind
+	err = sessions.BindUser(bob[0], "")
+	if err != nil {
+		t.Fatalf("Error: unbinding user:%s\n", err.Error())
+	}
+	store.Set(bob[0])
+
+	bob, err = si.UserSessions("bob")
+	if err != nil || len(bob) != 0 {
+		t.Fatalf("Error: expected 0 sessions for bob got %d (%v)\n", len(bob), err)
+	}
+}
+
+func TestLoginSessionLimit(t *testing.T) {
+	store := NewMapStore()
+
+	si := sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Store = store
+	si.MaxUserSessions = 2
+
+	evicted := make([]string, 0)
+	si.Hooks.OnDestroy = func(e sessions.SessionEvent) {
+		evicted = append(evicted, e.ID)
+	}
+
+	// Log alice in on a new request, saving the session as SaveSession would.
+	login := func(expiry time.Time) (sessions.Session, error) {
+		r, err := http.NewRequest("GET", "http://example.com", nil)
+		if err != nil {
+			t.Fatalf("Error: creating dummy request:%s\n", err.Error())
+		}
+		defer si.ClearCache(r)
+
+		if err := si.Login(r, "alice"); err != nil {
+			return nil, err
+		}
+
+		s, err := si.GetSession(r)
+		if err != nil {
+			t.Fatalf("Error: getting session:%s\n", err.Error())
+		}
+
+		s.SetExpiry(expiry)
+		if err := si.PersistSession(r); err != nil {
+			t.Fatalf("Error: persisting session:%s\n", err.Error())
+		}
+		return s, nil
+	}
+
+	oldest, err := login(time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("Error: logging in:%s\n", err.Error())
+	}
+
+	_, err = login(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Error: logging in:%s\n", err.Error())
+	}
+
+	_, err = login(time.Now().Add(time.Hour))
+	if err != sessions.ErrSessionLimit {
+		t.Fatalf("Error: expected session limit error got %v\n", err)
+	}
+
+	si.SessionLimitPolicy = sessions.EvictOldestSession
+	_, err = login(time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Error: logging in:%s\n", err.Error())
+	}
+
+	if len(store.Records) != 2 {
+		t.Fatalf("Error: expected 2 sessions got %d\n", len(store.Records))
+	}
+
+	id, _ := oldest.ID()
+	if _, ok := store.Records[id]; ok {
+		t.Fatalf("Error: oldest session not evicted\n")
+	}
+
+	if len(evicted) != 1 || evicted[0] != id {
+		t.Fatalf("Error: expected a destroy event for %s got %v\n", id, evicted)
+	}
+}
+
+func TestLoginNewID(t *testing.T) {
+	store := NewMapStore()
+
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Store = store
+
+	r := sessionstest.NewRequest(t, si, "GET", "/", nil, map[interface{}]interface{}{"Key": "Value", "sessions.csrf": "secret"})
+	before, err := si.GetSession(r)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+	oldid, _ := before.ID()
+
+	if err := si.Login(r, "alice"); err != nil {
+		t.Fatalf("Error: logging in:%s\n", err.Error())
+	}
+
+	after, err := si.GetSession(r)
+	if err != nil {
+		t.Fatalf("Error: getting session:%s\n", err.Error())
+	}
+
+	newid, _ := after.ID()
+	if newid == oldid {
+		t.Fatalf("Error: session id not changed on login\n")
+	}
+
+	if _, ok := store.Records[oldid]; ok {
+		t.Fatalf("Error: old session not deleted\n")
+	}
+
+	if v, _ := after.Get("Key"); v != "Value" {
+		t.Fatalf("Error: expected \"Value\" got \"%v\"\n", v)
+	}
+
+	if v, _ := after.Get("sessions.csrf"); v != nil {
+		t.Fatalf("Error: CSRF secret kept over login\n")
+	}
+
+	if !after.Expiry().Equal(before.Expiry()) {
+		t.Fatalf("Error: expected expiry %v got %v\n", before.Expiry(), after.Expiry())
+	}
+
+	if user, _ := sessions.SessionUser(after); user != "alice" {
+		t.Fatalf("Error: expected alice got %q\n", user)
+	}
+}
+
+func TestLoginRedirect(t *testing.T) {
+	store := NewMapStore()
+
+	si := &sessions.SessionInfo{}
+	si.Cookie.Name = "SESSIONID"
+	si.Timeout = time.Hour
+	si.Store = store
+
+	h := si.GetHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := si.Login(r, "alice"); err != nil {
+			t.Fatalf("Error: logging in:%s\n", err.Error())
+		}
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	}))
+
+	r := sessionstest.NewRequest(t, si, "POST", "/login", nil, map[interface{}]interface{}{"Key": "Value"})
+	oldid, _ := si.GetSessionID(r)
+
+	w := sessionstest.NewRecorder(si)
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusSeeOther {
+		t.Fatalf("Error: expected 303 got %d\n", w.Code)
+	}
+
+	// The client is left holding the new session.
+	s := w.Session(t)
+	if s == nil {
+		t.Fatalf("Error: no session after login, cookie %+v\n", w.Cookie())
+	}
+
+	newid, _ := s.ID()
+	if newid == oldid {
+		t.Fatalf("Error: session id not changed on login\n")
+	}
+
+	if user, _ := sessions.SessionUser(s); user != "alice" {
+		t.Fatalf("Error: expected alice got %q\n", user)
+	}
+
+	if _, ok := store.Records[oldid]; ok {
+		t.Fatalf("Error: old session not deleted\n")
+	}
+}